@@ -1,3 +1,5 @@
 module github.com/paulfdunn/goutil
 
 go 1.19
+
+require golang.org/x/text v0.14.0
@@ -1,19 +1,46 @@
 package goutil
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding"
 	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/fnv"
 	"io"
 	"math"
+	"net"
 	"net/http"
+	"net/textproto"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 const (
@@ -26,292 +53,2786 @@ const (
 	MaxInt = int(^uint(0) >> 1)
 	// MinInt is the minimum for an int
 	MinInt = -MaxInt - 1
+
+	// UUIDNamespaceDNS is the RFC 4122 namespace for UUIDv5 names that are domain names.
+	UUIDNamespaceDNS = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	// UUIDNamespaceURL is the RFC 4122 namespace for UUIDv5 names that are URLs.
+	UUIDNamespaceURL = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+
+	// compactJSONArraysWidth is the longest non-numeric scalar array CompactJSONArrays
+	// puts on a single line.
+	compactJSONArraysWidth = 80
 )
 
-// ByteSliceToIntSlice converts an byte slice to integer slice
-func ByteSliceToIntSlice(bytes []byte) []int {
-	out := make([]int, len(bytes))
-	for i := range bytes {
-		out[i] = int(bytes[i])
+var (
+	// ErrAllFiltered is returned, wrapped with the function name, when every input value
+	// is excluded by a filter; I.E. by MinMaxIntSlice. Test for it with errors.Is.
+	ErrAllFiltered = errors.New("all inputs were filtered")
+
+	// abbreviations are kept in all caps when converting to CamelCase.
+	abbreviations = []string{"JSON", "NQN", "HTTP"}
+
+	// emailRegexp is used by ExtractEmails.
+	emailRegexp = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// prettyJSONPlaceholder matches the placeholders made by prettyJSONProtectStrings.
+	prettyJSONPlaceholder = regexp.MustCompile("\x00[a-j]+\x00")
+	// urlRegexp is used by ExtractURLs.
+	urlRegexp = regexp.MustCompile(`(?i)\b(?:https?|ftp)://[^\s<>"'()]+`)
+
+	// textMarshalerType is used to find values that can be converted to text.
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// BloomFilter is a probabilistic set for approximate membership tests using much less
+// memory than an exact set. MaybeContains never returns false for an added value (no
+// false negatives), but may return true for a value that was never added (a false
+// positive), with a probability near the rate the filter was sized for as long as no
+// more than the expected number of values are added.
+// Hashes are derived from MD5Checksum using double hashing.
+// A BloomFilter is not safe for concurrent use.
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+// NewBloomFilter creates a BloomFilter sized to hold expected values with a false
+// positive rate of fpRate. expected less than 1 is treated as 1, and fpRate outside
+// of (0,1) is treated as 0.01.
+func NewBloomFilter(expected int, fpRate float64) *BloomFilter {
+	if expected < 1 {
+		expected = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+
+	// Optimal number of bits and hash functions.
+	m := math.Ceil(-float64(expected) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(expected) * math.Ln2)
+	if k < 1 {
+		k = 1
+	}
+	size := uint64(m)
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: uint64(k),
 	}
-	return out
 }
 
-// ByteSliceToString converts a byte slice to a hex string with bytesPerLine; no "0x" prefix.
-func ByteSliceToString(in []byte, bytesPerLine int) (out string) {
-	index := 0
-	// Convert bytes to ints, needed for formatting later.
-	inInts := make([]int, len(in))
-	for i, v := range in {
-		inInts[i] = int(v)
+// Add adds value to the filter.
+func (bf *BloomFilter) Add(value []byte) {
+	h1, h2 := bloomFilterHashes(value)
+	for i := uint64(0); i < bf.hashes; i++ {
+		bit := (h1 + i*h2) % bf.size
+		bf.bits[bit/64] |= 1 << (bit % 64)
 	}
+}
 
-	for {
-		if index >= len(inInts) {
-			break
+// MaybeContains returns false if value was definitely not added, and true if value
+// was probably added.
+func (bf *BloomFilter) MaybeContains(value []byte) bool {
+	h1, h2 := bloomFilterHashes(value)
+	for i := uint64(0); i < bf.hashes; i++ {
+		bit := (h1 + i*h2) % bf.size
+		if bf.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
 		}
+	}
+	return true
+}
 
-		// Print bytesPerLine, or a partial line if there are not enough bytes left.
-		end := index + bytesPerLine
-		if end > len(inInts) {
-			end = len(inInts)
-		}
+// bloomFilterHashes returns the two base hashes for double hashing.
+func bloomFilterHashes(value []byte) (uint64, uint64) {
+	sum := MD5Checksum(value)
+	// An odd second hash ensures successive probes are distinct.
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
 
-		// Ints format nicely with this; space separated.
-		s := fmt.Sprintf("%02x", inInts[index:end])
-		out += fmt.Sprintf("%s", s[1:len(s)-1])
-		out += "\n"
-		index += bytesPerLine
+// Chain collects multiple errors into one error. errors.Is and errors.As match an error
+// anywhere in the Chain, including errors wrapped within each error.
+type Chain []error
+
+// Add appends err to the Chain if it is not nil.
+func (c *Chain) Add(err error) {
+	if err != nil {
+		*c = append(*c, err)
 	}
-	return out
 }
 
-// ConvertCamelToUnderscore converts the input string in CamelCase to underscore format.
-func ConvertCamelToUnderscore(input string, allLower bool) (output string) {
-	for i := range input {
-		if len(input) >= i+2 && string(input[i]) == strings.ToLower(string(input[i])) &&
-			string(input[i+1]) == strings.ToUpper(string(input[i+1])) {
-			// Insert underscore between a lower and upper case character.
-			output += string(input[i]) + "_"
-		} else {
-			output += string(input[i])
-		}
+// Err returns the Chain as an error, or nil if the Chain is empty, so that an empty Chain
+// is not returned as a non-nil error.
+func (c Chain) Err() error {
+	if len(c) == 0 {
+		return nil
 	}
+	return c
+}
 
-	if allLower {
-		output = strings.ToLower(output)
+// Error returns the messages of the errors in the Chain separated by "; ".
+func (c Chain) Error() string {
+	msgs := make([]string, len(c))
+	for i, err := range c {
+		msgs[i] = err.Error()
 	}
-	return output
+	return strings.Join(msgs, "; ")
 }
 
-// ConvertJSONUnderscoreToCamel converts the input JSON string in underscore format
-// to CamelCase; only JSON keys are converted.
-func ConvertJSONUnderscoreToCamel(input string) (output string, err error) {
-	var inputObject map[string]interface{}
-	err = json.Unmarshal([]byte(input), &inputObject)
-	if err != nil {
-		return "", err
+// Unwrap returns the errors in the Chain.
+func (c Chain) Unwrap() []error {
+	return c
+}
+
+// Is reports whether any error in the Chain matches target, per errors.Is.
+func (c Chain) Is(target error) bool {
+	for _, err := range c {
+		if errors.Is(err, target) {
+			return true
+		}
 	}
+	return false
+}
 
-	outputObject, err := ConvertMapUnderscoreToCamel(inputObject)
-	if err != nil {
-		return "", err
+// As finds the first error in the Chain that matches target, per errors.As.
+func (c Chain) As(target interface{}) bool {
+	for _, err := range c {
+		if errors.As(err, target) {
+			return true
+		}
 	}
-	out, err := json.Marshal(outputObject)
-	return string(out), err
+	return false
 }
 
-// ConvertMapUnderscoreToCamel converts the input JSON map in underscore format
-// to CamelCase; only JSON keys are converted.
-func ConvertMapUnderscoreToCamel(input map[string]interface{}) (output map[string]interface{}, err error) {
-	output = make(map[string]interface{})
-	for k, v := range input {
-		if newV, ok := v.(map[string]interface{}); ok {
-			output[ConvertUnderscoreToCamel(k)], err = ConvertMapUnderscoreToCamel(newV)
-			if err != nil {
-				return output, err
-			}
-		} else if newV, ok := v.([]interface{}); ok {
-			out := make([]interface{}, 0)
-			for _, nv := range newV {
-				s, err := json.Marshal(nv)
-				if err != nil {
-					return output, err
-				}
+// EditOpType is the type of an EditOp.
+type EditOpType int
 
-				o, _ := ConvertJSONUnderscoreToCamel(string(s))
-				var sObj interface{}
-				err = json.Unmarshal([]byte(o), &sObj)
-				if err != nil {
-					return output, err
-				}
-				out = append(out, sObj)
-			}
-			output[ConvertUnderscoreToCamel(k)] = out
-		} else {
-			output[ConvertUnderscoreToCamel(k)] = v
-		}
+const (
+	// EditInsert inserts EditOp.New at EditOp.Position.
+	EditInsert EditOpType = iota
+	// EditDelete deletes EditOp.Old at EditOp.Position.
+	EditDelete
+	// EditSubstitute replaces EditOp.Old with EditOp.New at EditOp.Position.
+	EditSubstitute
+)
+
+// EditOp is a single rune edit operation as returned by EditOperations.
+// Position is the rune index in the string as transformed by all prior operations,
+// so applying the operations in order transforms the source into the target.
+type EditOp struct {
+	Type     EditOpType
+	Position int
+	Old      rune
+	New      rune
+}
+
+// String returns the name of the EditOpType.
+func (t EditOpType) String() string {
+	switch t {
+	case EditInsert:
+		return "insert"
+	case EditDelete:
+		return "delete"
+	case EditSubstitute:
+		return "substitute"
 	}
+	return fmt.Sprintf("EditOpType(%d)", int(t))
+}
 
-	return output, nil
+// HashRing is a consistent hash ring used to map keys to nodes such that adding or
+// removing a node only moves the keys belonging to that node. Each node is placed on
+// the ring at replicas virtual positions using MD5Checksum.
+// A HashRing is safe for concurrent use.
+type HashRing struct {
+	mu       sync.RWMutex
+	replicas int
+	hashes   []uint32
+	ring     map[uint32]string
+	nodes    map[string]struct{}
 }
 
-// ConvertUnderscoreToCamel converts a single input word from underscore format to CamelCase.
-func ConvertUnderscoreToCamel(input string) (output string) {
-	for i := range input {
-		if i == 0 && string(input[i]) != "_" {
-			// Capitalize first character if not underscore.
-			output += strings.ToUpper(string(input[i]))
-		} else if i == 0 && string(input[i]) == "_" {
-			// Skip leading underscore.
-		} else if i == 1 && string(input[i]) != "_" && string(input[i-1]) == "_" {
-			// Capitalize character after a leading underscore.
-			output += strings.ToUpper(string(input[i]))
-		} else if i >= 2 && string(input[i]) != "_" &&
-			string(input[i-1]) == "_" && string(input[i-2]) != "_" {
-			// Capitalize character after a underscore, where underscore is precedeed by
-			// non-underscore.
-			output += strings.ToUpper(string(input[i]))
-		} else if string(input[i]) == "_" {
-			// Skip underscores in output.
-		} else {
-			output += string(input[i])
+// NewHashRing creates an empty HashRing with replicas virtual nodes per node; replicas
+// less than 1 is treated as 1.
+func NewHashRing(replicas int) *HashRing {
+	if replicas < 1 {
+		replicas = 1
+	}
+	return &HashRing{
+		replicas: replicas,
+		ring:     map[uint32]string{},
+		nodes:    map[string]struct{}{},
+	}
+}
+
+// AddNode adds a node to the ring; adding an existing node is a no-op.
+func (hr *HashRing) AddNode(node string) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	if _, ok := hr.nodes[node]; ok {
+		return
+	}
+	hr.nodes[node] = struct{}{}
+
+	for i := 0; i < hr.replicas; i++ {
+		vnode := strconv.Itoa(i) + "#" + node
+		h := hashRingHash(vnode)
+		// A position owned by another virtual node is kept by its owner; re-salt until
+		// a free position is found, so every node has replicas positions.
+		for salt := 1; ; salt++ {
+			if _, ok := hr.ring[h]; !ok {
+				break
+			}
+			h = hashRingHash(vnode + "#" + strconv.Itoa(salt))
 		}
+		hr.hashes = append(hr.hashes, h)
+		hr.ring[h] = node
 	}
+	sort.Slice(hr.hashes, func(i, j int) bool { return hr.hashes[i] < hr.hashes[j] })
+}
 
-	// Abbreviations will be all caps.
-	abbreviations := []string{"JSON", "NQN", "HTTP"}
-	for _, abrv := range abbreviations {
-		output = regexp.MustCompile(fmt.Sprintf(`(?i)(%s)`, abrv)).ReplaceAllString(output, abrv)
+// GetNode returns the node that owns key, or "" if the ring is empty.
+func (hr *HashRing) GetNode(key string) string {
+	hr.mu.RLock()
+	defer hr.mu.RUnlock()
+	if len(hr.hashes) == 0 {
+		return ""
 	}
 
-	return output
+	h := hashRingHash(key)
+	// The owner is the first virtual node clockwise from the key, wrapping to the start.
+	i := sort.Search(len(hr.hashes), func(i int) bool { return hr.hashes[i] >= h })
+	if i == len(hr.hashes) {
+		i = 0
+	}
+	return hr.ring[hr.hashes[i]]
 }
 
-// DirIsEmpty returns true if the directory exists and is empty.
-func DirIsEmpty(path string) (bool, error) {
-	f, err := os.Open(path)
-	defer f.Close()
-	if err != nil {
-		// Return false if the directory does not exist.
-		return false, err
+// RemoveNode removes a node from the ring; removing a missing node is a no-op. Only
+// positions owned by node are removed.
+func (hr *HashRing) RemoveNode(node string) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	if _, ok := hr.nodes[node]; !ok {
+		return
 	}
+	delete(hr.nodes, node)
 
-	// Readdirnames does NOT return "." and ".."; so a single file indicates the dir
-	// is not empty.
-	_, err = f.Readdirnames(1)
-	if err == io.EOF {
-		return true, nil
+	hashes := make([]uint32, 0, len(hr.hashes))
+	for _, h := range hr.hashes {
+		if hr.ring[h] == node {
+			delete(hr.ring, h)
+			continue
+		}
+		hashes = append(hashes, h)
 	}
+	hr.hashes = hashes
+}
 
-	return false, err
+// hashRingHash hashes s to a position on a HashRing.
+func hashRingHash(s string) uint32 {
+	sum := MD5Checksum([]byte(s))
+	return binary.BigEndian.Uint32(sum[:4])
 }
 
-// EnumsFromMapIntString creates lists of keys and values from a map[int]string.
-func EnumsFromMapIntString(m map[int]string) (keys []int, values []string) {
-	keys = make([]int, len(m))
-	i := 0
-	for k := range m {
-		keys[i] = k
-		i++
-	}
-	sort.Ints(keys)
+// Semaphore limits concurrency to a fixed number of slots, backed by a buffered channel.
+// Multi-slot acquisitions are serialized so that concurrent callers cannot deadlock
+// each holding part of what they need.
+// A Semaphore is safe for concurrent use.
+type Semaphore struct {
+	slots chan struct{}
+	// acquiring is a selectable lock held while slots are being acquired.
+	acquiring chan struct{}
+}
 
-	for _, k := range keys {
-		values = append(values, m[k])
+// NewSemaphore creates a Semaphore with size slots; size less than 1 is treated as 1.
+func NewSemaphore(size int) *Semaphore {
+	if size < 1 {
+		size = 1
 	}
+	return &Semaphore{
+		slots:     make(chan struct{}, size),
+		acquiring: make(chan struct{}, 1),
+	}
+}
 
-	return keys, values
+// Acquire blocks until n slots are acquired.
+// Errors if n is larger than the size of the Semaphore.
+func (s *Semaphore) Acquire(n int) error {
+	return s.AcquireContext(context.Background(), n)
 }
 
-// InIntSlice checks if a int slice contains specific int.
-func InIntSlice(intToFind int, list []int) bool {
-	for _, v := range list {
-		if v == intToFind {
-			return true
+// AcquireContext blocks until n slots are acquired or ctx is done. When ctx is done
+// no slots are held and ctx.Err() is returned.
+// Errors if n is larger than the size of the Semaphore.
+func (s *Semaphore) AcquireContext(ctx context.Context, n int) error {
+	if n > cap(s.slots) {
+		return fmt.Errorf("Semaphore: cannot acquire %d of %d slots", n, cap(s.slots))
+	}
+
+	select {
+	case s.acquiring <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.acquiring }()
+
+	for i := 0; i < n; i++ {
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			s.Release(i)
+			return ctx.Err()
 		}
 	}
-	return false
+	return nil
 }
 
-// InStringSlice checks if a string slice contains specific string.
-func InStringSlice(stringToFind string, list []string) bool {
-	for _, v := range list {
-		if v == stringToFind {
-			return true
+// Release releases n slots. Panics if more slots are released than are held.
+func (s *Semaphore) Release(n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.slots:
+		default:
+			panic("Semaphore: released more slots than held")
 		}
 	}
-	return false
 }
 
-// InStringSlicePtr checks if a string slice contains specific string.
-func InStringSlicePtr(stringToFind string, list []*string) bool {
-	values := make([]string, 0)
-	for _, v := range list {
-		values = append(values, *v)
+// TryAcquire acquires n slots without blocking; returns true if the slots were
+// acquired, false otherwise.
+func (s *Semaphore) TryAcquire(n int) bool {
+	if n > cap(s.slots) {
+		return false
 	}
-	return InStringSlice(stringToFind, values)
-}
 
-// IntSliceIsASCII tests an integer slice to see if all values are in the printable
-// ASCII range; returns true if yes, false otherwise.
-// filter is used to filter out values, like 0 that is
-// used for padding some string and is used for EOF; also used
-// to filter CR/LF/etc.
-// filter is a map mainly so text can be added to describe why a value is
-// filtered out, but the text is not required.
-// If the input contains ONLY filtered values, returns false
-// Erors if the input is an empty slice, or if all input values are filtered out.
-func IntSliceIsASCII(in []int, filter map[int]string) (bool, error) {
-	min, max, err := MinMaxIntSlice(in, filter)
-	// Cannot include 127, which is DEL, or text compares on binary
-	// data will fail when a single field includes 0x7f
-	if min >= 32 && max < 127 {
-		return true, err
+	select {
+	case s.acquiring <- struct{}{}:
+	default:
+		return false
 	}
-	return false, err
-}
+	defer func() { <-s.acquiring }()
 
-// IntSliceRemoveDuplicates removes duplicates from to integer slices; results may not be stable.
-func IntSliceRemoveDuplicates(in []int) []int {
-	// Merge, and use a map to eliminate duplicates.
-	m := map[int]int{}
-	for i := range in {
-		if _, ok := m[in[i]]; ok {
-			m[in[i]]++
-		} else {
-			m[in[i]] = 1
+	for i := 0; i < n; i++ {
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			s.Release(i)
+			return false
 		}
 	}
-	var index int
-	newAll := make([]int, len(m))
-	for k := range m {
-		newAll[index] = k
-		index++
-	}
-	return newAll
+	return true
 }
 
-// MD5Checksum provides a []byte with the MD5 hash (checksum) for the input.
-func MD5Checksum(input []byte) [16]byte {
-	return md5.Sum(input)
+// Set is a set of comparable values. Set methods that return a Set return a new Set and
+// do not modify their operands.
+type Set[T comparable] map[T]struct{}
+
+// NewSet returns a Set containing items.
+func NewSet[T comparable](items ...T) Set[T] {
+	s := make(Set[T], len(items))
+	s.Add(items...)
+	return s
 }
 
-// MD5ChecksumBase64 provides a string with the MD5 hash (checksum) in base64 for the input.
-func MD5ChecksumBase64(input []byte) string {
-	s := MD5Checksum(input)
-	return base64.StdEncoding.EncodeToString(s[:])
+// Add adds items to the Set.
+func (s Set[T]) Add(items ...T) {
+	for _, item := range items {
+		s[item] = struct{}{}
+	}
 }
 
-// MinMaxIntSlice returns the max and min for an int slice.
-// Errors if the input is an empty slice, or if all input values are filtered out.
-// filter is used to filter out specific values; it is a map mainly
-// so text can be added to describe why a value is filtered out,
-// but the text is not required.
-func MinMaxIntSlice(in []int, filter map[int]string) (int, int, error) {
-	var max = MinInt
-	var min = MaxInt
-	var found = false
-	var err error
-	for _, value := range in {
-		// Skip values in the filter
-		if _, ok := filter[value]; ok {
-			continue
-		}
-		found = true
-		if max < value {
-			max = value
+// Contains returns true if item is in the Set.
+func (s Set[T]) Contains(item T) bool {
+	_, ok := s[item]
+	return ok
+}
+
+// Difference returns the items in the Set that are not in other.
+func (s Set[T]) Difference(other Set[T]) Set[T] {
+	out := make(Set[T])
+	for item := range s {
+		if !other.Contains(item) {
+			out[item] = struct{}{}
 		}
-		if min > value {
-			min = value
+	}
+	return out
+}
+
+// Intersect returns the items in both the Set and other.
+func (s Set[T]) Intersect(other Set[T]) Set[T] {
+	out := make(Set[T])
+	for item := range s {
+		if other.Contains(item) {
+			out[item] = struct{}{}
 		}
 	}
+	return out
+}
 
-	if !found {
-		err = errors.New("MinMaxIntSlice: all inputs were filtered")
+// Len returns the number of items in the Set.
+func (s Set[T]) Len() int {
+	return len(s)
+}
+
+// Remove removes items from the Set; removing an item not in the Set is a no-op.
+func (s Set[T]) Remove(items ...T) {
+	for _, item := range items {
+		delete(s, item)
 	}
+}
 
-	return min, max, err
+// Slice returns the items in the Set in unspecified order; sort the result if a stable
+// order is needed.
+func (s Set[T]) Slice() []T {
+	out := make([]T, 0, len(s))
+	for item := range s {
+		out = append(out, item)
+	}
+	return out
+}
+
+// Union returns the items in either the Set or other.
+func (s Set[T]) Union(other Set[T]) Set[T] {
+	out := make(Set[T], len(s)+len(other))
+	for item := range s {
+		out[item] = struct{}{}
+	}
+	for item := range other {
+		out[item] = struct{}{}
+	}
+	return out
+}
+
+// StatusRecorder wraps an http.ResponseWriter to record the status code of the response,
+// I.E. for access logging middleware.
+type StatusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// NewStatusRecorder returns a StatusRecorder writing to w.
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w}
+}
+
+// Status returns the status code of the response; http.StatusOK if Write was called
+// without WriteHeader, or nothing has been written, as that is what net/http sends.
+func (s *StatusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+// Unwrap returns the wrapped http.ResponseWriter.
+func (s *StatusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Write records http.StatusOK if WriteHeader has not been called, and writes b.
+func (s *StatusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// WriteHeader records code, if it is the first final (not 1xx informational) status
+// code, and writes it.
+func (s *StatusRecorder) WriteHeader(code int) {
+	if s.status == 0 && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Style is an identifier case style used by Recase.
+type Style int
+
+const (
+	// StyleCamel is CamelCase.
+	StyleCamel Style = iota
+	// StyleLowerCamel is lowerCamelCase.
+	StyleLowerCamel
+	// StyleSnake is snake_case.
+	StyleSnake
+	// StyleScreamingSnake is SCREAMING_SNAKE_CASE.
+	StyleScreamingSnake
+	// StyleKebab is kebab-case.
+	StyleKebab
+	// StyleTitle is Title Case, space separated.
+	StyleTitle
+)
+
+// TokenBucket is a token bucket rate limiter; tokens are added continuously at rate per
+// second up to burst, and each allowed event takes one token.
+// A TokenBucket is safe for concurrent use.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a TokenBucket that allows rate events per second with bursts of
+// up to burst events; the bucket starts full. burst less than 1 is treated as 1.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow takes a token and returns true if one is available, otherwise returns false
+// without blocking.
+func (tb *TokenBucket) Allow() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill()
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return true
+	}
+	return false
+}
+
+// Wait blocks until a token is available and takes it, or until ctx is done, in which
+// case ctx.Err() is returned.
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		tb.mu.Lock()
+		tb.refill()
+		if tb.tokens >= 1 {
+			tb.tokens--
+			tb.mu.Unlock()
+			return nil
+		}
+		// With no rate, tokens are never added; wait for ctx.
+		var timer <-chan time.Time
+		if tb.rate > 0 {
+			wait := time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+			timer = time.After(wait)
+		}
+		tb.mu.Unlock()
+
+		select {
+		case <-timer:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// refill adds the tokens accumulated since the last refill; tb.mu must be held.
+func (tb *TokenBucket) refill() {
+	now := time.Now()
+	if tb.rate > 0 {
+		tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+		if tb.tokens > tb.burst {
+			tb.tokens = tb.burst
+		}
+	}
+	tb.last = now
+}
+
+// UnitConverter converts values between units defined by a table of unit name to
+// factor, where the factor is the size of the unit in a common base unit. I.E. for
+// bytes {"B": 1, "KiB": 1024, "MiB": 1048576}.
+type UnitConverter struct {
+	factors map[string]float64
+	digits  int
+}
+
+// NewUnitConverter creates a UnitConverter from factors; converted values are rounded
+// to digits using Round. factors is copied, so later changes to it have no effect.
+func NewUnitConverter(factors map[string]float64, digits int) *UnitConverter {
+	uc := &UnitConverter{factors: make(map[string]float64, len(factors)), digits: digits}
+	for k, v := range factors {
+		uc.factors[k] = v
+	}
+	return uc
+}
+
+// Convert returns value, in units of from, converted to units of to.
+// Errors if either unit is unknown, or has a factor that is not positive and finite.
+func (uc *UnitConverter) Convert(value float64, from, to string) (float64, error) {
+	fromFactor, err := uc.factor(from)
+	if err != nil {
+		return 0, err
+	}
+	toFactor, err := uc.factor(to)
+	if err != nil {
+		return 0, err
+	}
+	return Round(value*fromFactor/toFactor, uc.digits), nil
+}
+
+// factor returns the factor for unit, or an error if it is unknown or invalid.
+func (uc *UnitConverter) factor(unit string) (float64, error) {
+	f, ok := uc.factors[unit]
+	if !ok {
+		return 0, fmt.Errorf("UnitConverter: unknown unit %q", unit)
+	}
+	if f <= 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("UnitConverter: invalid factor %v for unit %q", f, unit)
+	}
+	return f, nil
+}
+
+// Base64DecodeAny decodes s as base64 with either the standard or URL safe alphabet,
+// with or without padding; the encodings are tried in the order StdEncoding,
+// URLEncoding, RawStdEncoding, and RawURLEncoding, and the first successful decode is
+// returned. The alphabets differ only in "+/" and "-_", so s decodes the same with any
+// encoding that accepts it. An empty s returns empty bytes.
+// Errors if s is not valid with any of the encodings.
+func Base64DecodeAny(s string) ([]byte, error) {
+	var err error
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding,
+		base64.RawStdEncoding, base64.RawURLEncoding} {
+		var b []byte
+		if b, err = enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("Base64DecodeAny: %w", err)
+}
+
+// BlockDiff splits oldData and newData into blocks of blockSize bytes and returns the
+// indices of the blocks whose SHA1Checksum differ. Blocks are aligned to offset 0, so
+// block i covers bytes [i*blockSize, (i+1)*blockSize); the final block may be short.
+// Blocks that exist in only one input are returned as differing. Because blocks are
+// fixed, an insertion or deletion changes every block after it.
+// Returns nil if blockSize is not positive.
+func BlockDiff(oldData, newData []byte, blockSize int) []int {
+	if blockSize <= 0 {
+		return nil
+	}
+	block := func(data []byte, i int) []byte {
+		start := i * blockSize
+		if start >= len(data) {
+			return nil
+		}
+		end := start + blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		return data[start:end]
+	}
+
+	n := len(oldData)
+	if len(newData) > n {
+		n = len(newData)
+	}
+	out := []int{}
+	for i := 0; i*blockSize < n; i++ {
+		o, nw := block(oldData, i), block(newData, i)
+		if o == nil || nw == nil || SHA1Checksum(o) != SHA1Checksum(nw) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// ByteSimilarity returns an estimate, from 0 to 1, of how similar a and b are; 1 for
+// identical content and near 0 for unrelated content.
+// Each input is split into overlapping 4 byte shingles that are hashed with
+// FNV64Checksum, and the score is the MinHash estimate, using 128 hash functions, of the
+// Jaccard similarity of the two sets of shingles. The standard error of the estimate is
+// about 0.09, so changes to a small fraction of a large input may still score 1, and a
+// score of 1 does not mean the inputs are equal. Shingles are compared as sets, so
+// reordering large blocks of an input changes the score very little.
+// Inputs shorter than a shingle are treated as a single shingle. Two empty inputs score 1.
+func ByteSimilarity(a, b []byte) float64 {
+	sigA, sigB := byteSimilaritySignature(a), byteSimilaritySignature(b)
+	if sigA == nil || sigB == nil {
+		if sigA == nil && sigB == nil {
+			return 1
+		}
+		return 0
+	}
+	same := 0
+	for i := range sigA {
+		if sigA[i] == sigB[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(sigA))
+}
+
+// byteSimilaritySignature returns the MinHash signature of data for ByteSimilarity, or
+// nil for empty data.
+func byteSimilaritySignature(data []byte) []uint64 {
+	const shingle = 4
+	const hashes = 128
+	if len(data) == 0 {
+		return nil
+	}
+
+	shingles := map[uint64]struct{}{}
+	if len(data) < shingle {
+		shingles[FNV64Checksum(data)] = struct{}{}
+	}
+	for i := 0; i+shingle <= len(data); i++ {
+		shingles[FNV64Checksum(data[i:i+shingle])] = struct{}{}
+	}
+
+	sig := make([]uint64, hashes)
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	for h := range shingles {
+		for i := range sig {
+			// Derive each hash function by mixing h with a per function seed, using the
+			// splitmix64 finalizer.
+			x := h ^ (uint64(i+1) * 0x9e3779b97f4a7c15)
+			x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+			x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+			x ^= x >> 31
+			if x < sig[i] {
+				sig[i] = x
+			}
+		}
+	}
+	return sig
+}
+
+// ByteSliceIsASCII is IntSliceIsASCII for a byte slice, without converting to an
+// integer slice; returns true if all values not in filter are in the printable ASCII
+// range, 32 to 126, false otherwise.
+// Errors if the input is an empty slice, or if all input values are filtered out; the
+// error wraps ErrAllFiltered.
+func ByteSliceIsASCII(in []byte, filter map[byte]string) (bool, error) {
+	found := false
+	for _, value := range in {
+		if _, ok := filter[value]; ok {
+			continue
+		}
+		found = true
+		if value < 32 || value > 126 {
+			return false, nil
+		}
+	}
+	if !found {
+		return false, Wrap(ErrAllFiltered, "ByteSliceIsASCII")
+	}
+	return true, nil
+}
+
+// ByteSliceToIntSlice converts an byte slice to integer slice
+func ByteSliceToIntSlice(bytes []byte) []int {
+	out := make([]int, len(bytes))
+	for i := range bytes {
+		out[i] = int(bytes[i])
+	}
+	return out
+}
+
+// ByteSliceToString converts a byte slice to a hex string with bytesPerLine; no "0x" prefix.
+func ByteSliceToString(in []byte, bytesPerLine int) (out string) {
+	return ByteSliceToStringSep(in, bytesPerLine, "\n", true)
+}
+
+// ByteSliceToStringSep converts a byte slice to a hex string with bytesPerLine, with
+// lines separated by sep; no "0x" prefix. When trailingSep is true sep is also appended
+// after the final line.
+// A bytesPerLine <= 0 puts all bytes on a single line.
+func ByteSliceToStringSep(in []byte, bytesPerLine int, sep string, trailingSep bool) (out string) {
+	return byteSliceFormat(in, bytesPerLine, "%02x", sep, trailingSep)
+}
+
+// ByteSliceToStringFormatted converts a byte slice to a hex string with bytesPerLine,
+// like ByteSliceToString. When prefix is true each byte is prefixed with "0x"; when
+// upper is true hex digits are upper case.
+func ByteSliceToStringFormatted(in []byte, bytesPerLine int, prefix bool, upper bool) string {
+	format := "%02x"
+	if upper {
+		format = "%02X"
+	}
+	if prefix {
+		format = "0x" + format
+	}
+	return byteSliceFormat(in, bytesPerLine, format, "\n", true)
+}
+
+// byteSliceFormat formats each byte with byteFormat, space separated, with bytesPerLine
+// bytes per line and lines separated by sep.
+func byteSliceFormat(in []byte, bytesPerLine int, byteFormat string, sep string, trailingSep bool) (out string) {
+	if bytesPerLine <= 0 {
+		bytesPerLine = len(in)
+	}
+
+	index := 0
+	for {
+		if index >= len(in) {
+			break
+		}
+
+		// Print bytesPerLine, or a partial line if there are not enough bytes left.
+		end := index + bytesPerLine
+		if end > len(in) {
+			end = len(in)
+		}
+
+		line := make([]string, 0, end-index)
+		for _, b := range in[index:end] {
+			line = append(line, fmt.Sprintf(byteFormat, b))
+		}
+		out += strings.Join(line, " ")
+		if end < len(in) || trailingSep {
+			out += sep
+		}
+		index += bytesPerLine
+	}
+	return out
+}
+
+// CRC32Checksum provides the CRC32 checksum of the input using the IEEE polynomial, as
+// used by Ethernet, gzip, and PNG. It is fast, but not suitable where collisions can be
+// forced; use SHA1Checksum or better there.
+func CRC32Checksum(input []byte) uint32 {
+	return crc32.ChecksumIEEE(input)
+}
+
+// CRC32ChecksumString provides the CRC32Checksum of the input as 8 lower case hex digits.
+func CRC32ChecksumString(input []byte) string {
+	return fmt.Sprintf("%08x", CRC32Checksum(input))
+}
+
+// CacheKey returns a deterministic key for parts, suitable for memoization; equal
+// parts always produce the same key. Parts are JSON encoded, so map keys are sorted and
+// numbers compare by value (int 1 and float64 1 are equal), while a string "1" and a
+// number 1 are different. Parts that cannot be JSON encoded, I.E. functions and
+// channels, are encoded with their type and fmt %v representation.
+// The key is the hex SHA1Checksum of the encoded parts.
+func CacheKey(parts ...interface{}) string {
+	encoded := make([]json.RawMessage, len(parts))
+	for i, p := range parts {
+		b, err := json.Marshal(p)
+		if err != nil {
+			b, _ = json.Marshal(fmt.Sprintf("%T:%v", p, p))
+		}
+		encoded[i] = b
+	}
+	// An array of the encoded parts is unambiguous, where concatenation may not be.
+	b, _ := json.Marshal(encoded)
+	return fmt.Sprintf("%x", SHA1Checksum(b))
+}
+
+// CanonicalJSON returns data in a canonical form; object keys are sorted and all
+// insignificant whitespace is removed, so equivalent documents produce identical bytes.
+// Numbers are preserved exactly as written and are not normalized; I.E. 1.0 and 1 differ.
+// Errors if data is not valid JSON.
+func CanonicalJSON(data []byte) ([]byte, error) {
+	v, err := decodeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("CanonicalJSON: %w", err)
+	}
+	return json.Marshal(v)
+}
+
+// decodeJSON decodes data, which must be a single JSON value, with numbers as
+// json.Number so they are preserved exactly as written.
+func decodeJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if err := jsonEnd(d); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// jsonEnd returns an error if d has data after the top-level value it has read.
+func jsonEnd(d *json.Decoder) error {
+	if _, err := d.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level value")
+	}
+	return nil
+}
+
+// CanonicalizeHeaders returns a copy of h with every key converted by
+// textproto.CanonicalMIMEHeaderKey; values of keys differing only in case are merged
+// under the canonical key. Merged values are appended in sorted order of the original
+// keys so the result is deterministic.
+func CanonicalizeHeaders(h map[string][]string) map[string][]string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(map[string][]string, len(h))
+	for _, k := range keys {
+		ck := textproto.CanonicalMIMEHeaderKey(k)
+		out[ck] = append(out[ck], h[k]...)
+	}
+	return out
+}
+
+// ChunkSlice splits in into consecutive chunks of size elements; the last chunk has
+// the remainder, if any. A size <= 0 returns all of in as one chunk, and an empty in
+// returns an empty, non-nil result. Chunks share the backing array of in, but are capped
+// so appending to a chunk does not overwrite the next one.
+func ChunkSlice[T any](in []T, size int) [][]T {
+	chunks := make([][]T, 0)
+	if len(in) == 0 {
+		return chunks
+	}
+	if size <= 0 {
+		size = len(in)
+	}
+	for start := 0; start < len(in); start += size {
+		end := start + size
+		if end > len(in) {
+			end = len(in)
+		}
+		chunks = append(chunks, in[start:end:end])
+	}
+	return chunks
+}
+
+// ClosestString returns the element of candidates with the smallest
+// LevenshteinDistance to target, and that distance; ties go to the earliest element.
+// Returns "" and -1 if candidates is empty.
+func ClosestString(target string, candidates []string) (string, int) {
+	best, bestDistance := "", -1
+	for _, c := range candidates {
+		d := LevenshteinDistance(target, c)
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = c, d
+			if d == 0 {
+				break
+			}
+		}
+	}
+	return best, bestDistance
+}
+
+// CollapseRepeatedLines copies r to w, replacing each run of consecutive identical lines
+// with the first line of the run followed by a "(repeated N times)" line, where N is
+// the length of the run. Other lines are copied verbatim, including line endings; "\n"
+// and "\r\n" endings are ignored when comparing lines.
+func CollapseRepeatedLines(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	prev, prevKey := "", ""
+	count := 0
+	flush := func() error {
+		if count == 0 {
+			return nil
+		}
+		if _, err := io.WriteString(w, prev); err != nil {
+			return err
+		}
+		if count > 1 {
+			if !strings.HasSuffix(prev, "\n") {
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "(repeated %d times)\n", count); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			key := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if count > 0 && key == prevKey {
+				count++
+			} else {
+				if ferr := flush(); ferr != nil {
+					return fmt.Errorf("CollapseRepeatedLines: %w", ferr)
+				}
+				prev, prevKey, count = line, key, 1
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("CollapseRepeatedLines: %w", err)
+		}
+	}
+	if err := flush(); err != nil {
+		return fmt.Errorf("CollapseRepeatedLines: %w", err)
+	}
+	return nil
+}
+
+// CompactJSONArrays returns input indented with two spaces per level, like
+// json.MarshalIndent, except that arrays of numbers, and arrays of other scalars
+// that fit in 80 bytes, are written on a single line. I.E. "[1.5,-2,3e-7]".
+// Object key order and the text of keys and scalar values, including escapes, are
+// preserved.
+// Errors if input is not valid JSON.
+func CompactJSONArrays(input []byte) ([]byte, error) {
+	if !json.Valid(input) {
+		return nil, errors.New("CompactJSONArrays: invalid JSON")
+	}
+	var buf bytes.Buffer
+	if err := compactJSONValue(&buf, input, ""); err != nil {
+		return nil, fmt.Errorf("CompactJSONArrays: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// compactJSONValue writes the valid JSON value raw to buf at the indent level for
+// CompactJSONArrays.
+func compactJSONValue(buf *bytes.Buffer, raw []byte, indent string) error {
+	raw = bytes.TrimSpace(raw)
+	switch raw[0] {
+	case '{':
+		d := json.NewDecoder(bytes.NewReader(raw))
+		if _, err := d.Token(); err != nil {
+			return err
+		}
+		buf.WriteString("{")
+		n := 0
+		for ; d.More(); n++ {
+			// Write the key as it is in raw; re-encoding it could change escapes.
+			start := d.InputOffset()
+			if _, err := d.Token(); err != nil {
+				return err
+			}
+			key := bytes.TrimLeft(raw[start:d.InputOffset()], " \t\r\n,")
+			var v json.RawMessage
+			if err := d.Decode(&v); err != nil {
+				return err
+			}
+			if n > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n" + indent + "  ")
+			buf.Write(key)
+			buf.WriteString(": ")
+			if err := compactJSONValue(buf, v, indent+"  "); err != nil {
+				return err
+			}
+		}
+		if n > 0 {
+			buf.WriteString("\n" + indent)
+		}
+		buf.WriteString("}")
+	case '[':
+		d := json.NewDecoder(bytes.NewReader(raw))
+		if _, err := d.Token(); err != nil {
+			return err
+		}
+		elems := [][]byte{}
+		scalar, numeric := true, true
+		for d.More() {
+			var v json.RawMessage
+			if err := d.Decode(&v); err != nil {
+				return err
+			}
+			elems = append(elems, v)
+			switch c := v[0]; {
+			case c == '{' || c == '[':
+				scalar, numeric = false, false
+			case c != '-' && (c < '0' || c > '9'):
+				numeric = false
+			}
+		}
+		if scalar {
+			line := bytes.Join(elems, []byte(","))
+			if numeric || len(line)+2 <= compactJSONArraysWidth {
+				buf.WriteString("[")
+				buf.Write(line)
+				buf.WriteString("]")
+				return nil
+			}
+		}
+		buf.WriteString("[")
+		for i, v := range elems {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n" + indent + "  ")
+			if err := compactJSONValue(buf, v, indent+"  "); err != nil {
+				return err
+			}
+		}
+		if len(elems) > 0 {
+			buf.WriteString("\n" + indent)
+		}
+		buf.WriteString("]")
+	default:
+		buf.Write(raw)
+	}
+	return nil
+}
+
+// ConvertCamelToUnderscore converts the input string in CamelCase to underscore format.
+func ConvertCamelToUnderscore(input string, allLower bool) (output string) {
+	for i := range input {
+		if len(input) >= i+2 && string(input[i]) == strings.ToLower(string(input[i])) &&
+			string(input[i+1]) == strings.ToUpper(string(input[i+1])) {
+			// Insert underscore between a lower and upper case character.
+			output += string(input[i]) + "_"
+		} else {
+			output += string(input[i])
+		}
+	}
+
+	if allLower {
+		output = strings.ToLower(output)
+	}
+	return output
+}
+
+// ConvertJSONUnderscoreToCamel converts the input JSON string in underscore format
+// to CamelCase; only JSON keys are converted.
+func ConvertJSONUnderscoreToCamel(input string) (output string, err error) {
+	var inputObject map[string]interface{}
+	err = json.Unmarshal([]byte(input), &inputObject)
+	if err != nil {
+		return "", err
+	}
+
+	outputObject, err := ConvertMapUnderscoreToCamel(inputObject)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(outputObject)
+	return string(out), err
+}
+
+// ConvertMapUnderscoreToCamel converts the input JSON map in underscore format
+// to CamelCase; only JSON keys are converted.
+func ConvertMapUnderscoreToCamel(input map[string]interface{}) (output map[string]interface{}, err error) {
+	output = make(map[string]interface{})
+	for k, v := range input {
+		if newV, ok := v.(map[string]interface{}); ok {
+			output[ConvertUnderscoreToCamel(k)], err = ConvertMapUnderscoreToCamel(newV)
+			if err != nil {
+				return output, err
+			}
+		} else if newV, ok := v.([]interface{}); ok {
+			out := make([]interface{}, 0)
+			for _, nv := range newV {
+				s, err := json.Marshal(nv)
+				if err != nil {
+					return output, err
+				}
+
+				o, _ := ConvertJSONUnderscoreToCamel(string(s))
+				var sObj interface{}
+				err = json.Unmarshal([]byte(o), &sObj)
+				if err != nil {
+					return output, err
+				}
+				out = append(out, sObj)
+			}
+			output[ConvertUnderscoreToCamel(k)] = out
+		} else {
+			output[ConvertUnderscoreToCamel(k)] = v
+		}
+	}
+
+	return output, nil
+}
+
+// ConvertUnderscoreToCamel converts a single input word from underscore format to CamelCase.
+func ConvertUnderscoreToCamel(input string) (output string) {
+	for i := range input {
+		if i == 0 && string(input[i]) != "_" {
+			// Capitalize first character if not underscore.
+			output += strings.ToUpper(string(input[i]))
+		} else if i == 0 && string(input[i]) == "_" {
+			// Skip leading underscore.
+		} else if i == 1 && string(input[i]) != "_" && string(input[i-1]) == "_" {
+			// Capitalize character after a leading underscore.
+			output += strings.ToUpper(string(input[i]))
+		} else if i >= 2 && string(input[i]) != "_" &&
+			string(input[i-1]) == "_" && string(input[i-2]) != "_" {
+			// Capitalize character after a underscore, where underscore is precedeed by
+			// non-underscore.
+			output += strings.ToUpper(string(input[i]))
+		} else if string(input[i]) == "_" {
+			// Skip underscores in output.
+		} else {
+			output += string(input[i])
+		}
+	}
+
+	// Abbreviations will be all caps.
+	for _, abrv := range abbreviations {
+		output = regexp.MustCompile(fmt.Sprintf(`(?i)(%s)`, abrv)).ReplaceAllString(output, abrv)
+	}
+
+	return output
+}
+
+// CopyFile copies src to dst, streaming the content, and returns the number of bytes
+// copied. dst is created or truncated, and has the permission bits of src.
+// Errors if src does not exist, the directory of dst does not exist, or src and dst are
+// the same file.
+func CopyFile(src, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, fmt.Errorf("CopyFile: %w", err)
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("CopyFile: %w", err)
+	}
+	// Truncating dst would empty src if they are the same file.
+	if dstInfo, err := os.Stat(dst); err == nil && os.SameFile(fi, dstInfo) {
+		return 0, fmt.Errorf("CopyFile: %s and %s are the same file", src, dst)
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return 0, fmt.Errorf("CopyFile: %w", err)
+	}
+	n, err := io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, fmt.Errorf("CopyFile: %w", err)
+	}
+	// An existing dst keeps its mode, and a new one is modified by the umask.
+	if err := os.Chmod(dst, fi.Mode().Perm()); err != nil {
+		return n, fmt.Errorf("CopyFile: %w", err)
+	}
+	return n, nil
+}
+
+// CountFileLines returns CountLines for the file at path.
+func CountFileLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("CountFileLines: %w", err)
+	}
+	defer f.Close()
+	n, err := CountLines(f)
+	if err != nil {
+		return n, fmt.Errorf("CountFileLines: %w", err)
+	}
+	return n, nil
+}
+
+// CountLines returns the number of lines read from r; lines end in "\n" (so "\r\n" is
+// one line ending), and a final line without one is counted. Lines of any length are
+// supported, as only the count is kept. Empty input has 0 lines.
+func CountLines(r io.Reader) (int, error) {
+	buf := make([]byte, 32*1024)
+	count := 0
+	var last byte = '\n'
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			count += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("CountLines: %w", err)
+		}
+	}
+	if last != '\n' {
+		count++
+	}
+	return count, nil
+}
+
+// CreateTempDir creates a new directory in the default directory for temporary files,
+// see os.MkdirTemp, and returns its path and a cleanup func that removes it and all of
+// its contents. cleanup may be called more than once.
+func CreateTempDir(prefix string) (path string, cleanup func() error, err error) {
+	path, err = os.MkdirTemp("", prefix)
+	if err != nil {
+		return "", nil, fmt.Errorf("CreateTempDir: %w", err)
+	}
+	cleanup = func() error {
+		// RemoveAll returns nil if path does not exist.
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("CreateTempDir: cleanup: %w", err)
+		}
+		return nil
+	}
+	return path, cleanup, nil
+}
+
+// DeepCopyJSONMap returns a copy of m that shares no maps or slices with m, made by
+// marshaling m to JSON and unmarshaling the result, so values in the copy are the types
+// json.Unmarshal produces; I.E. numbers are float64 and structs are maps. A nil m returns
+// a nil map. Errors if m cannot be marshaled to JSON.
+func DeepCopyJSONMap(m map[string]interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("DeepCopyJSONMap: %w", err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("DeepCopyJSONMap: %w", err)
+	}
+	return out, nil
+}
+
+// DeinterleaveString is the inverse of InterleaveStrings; runes at even indexes are
+// returned in a, runes at odd indexes in b.
+// Errors if the input does not have an even number of runes.
+func DeinterleaveString(s string) (a string, b string, err error) {
+	runes := []rune(s)
+	if len(runes)%2 != 0 {
+		return "", "", fmt.Errorf("DeinterleaveString: odd rune count %d", len(runes))
+	}
+
+	ra := make([]rune, 0, len(runes)/2)
+	rb := make([]rune, 0, len(runes)/2)
+	for i := 0; i < len(runes); i += 2 {
+		ra = append(ra, runes[i])
+		rb = append(rb, runes[i+1])
+	}
+	return string(ra), string(rb), nil
+}
+
+// DetectContentType returns the MIME type of data using http.DetectContentType, which
+// considers at most the first 512 bytes. Empty data returns "application/octet-stream".
+func DetectContentType(data []byte) string {
+	if len(data) == 0 {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(data)
+}
+
+// DetectFileContentType returns the DetectContentType of the first 512 bytes of the file
+// at path; shorter files are read completely.
+func DetectFileContentType(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("DetectFileContentType: %w", err)
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("DetectFileContentType: %w", err)
+	}
+	return DetectContentType(buf[:n]), nil
+}
+
+// DetectIndent samples the leading whitespace of each line in data to determine the
+// indentation unit; unit is "\t" or " " and size is the number of units per indent level.
+// Tabs are chosen when more lines are tab indented than space indented; for spaces
+// the size is the most common change in indentation between lines (smallest on a tie).
+// Returns " ", 4 if data has no indented lines.
+func DetectIndent(data []byte) (unit string, size int) {
+	tabLines := 0
+	spaceLines := 0
+	deltas := map[int]int{}
+	previous := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		switch line[0] {
+		case '\t':
+			tabLines++
+			continue
+		case ' ':
+			spaceLines++
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		delta := indent - previous
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta > 0 {
+			deltas[delta]++
+		}
+		previous = indent
+	}
+
+	if tabLines == 0 && spaceLines == 0 {
+		return " ", 4
+	}
+	if tabLines > spaceLines {
+		return "\t", 1
+	}
+
+	bestCount := 0
+	for delta, count := range deltas {
+		if count > bestCount || (count == bestCount && delta < size) {
+			size = delta
+			bestCount = count
+		}
+	}
+	return " ", size
+}
+
+// DiffMaps compares two maps; added contains keys only in newMap, removed contains keys
+// only in oldMap (with the old values), and changed contains keys in both with differing
+// values (with the new values).
+func DiffMaps[K comparable, V comparable](oldMap, newMap map[K]V) (added, removed, changed map[K]V) {
+	added = map[K]V{}
+	removed = map[K]V{}
+	changed = map[K]V{}
+	for k, ov := range oldMap {
+		nv, ok := newMap[k]
+		if !ok {
+			removed[k] = ov
+		} else if nv != ov {
+			changed[k] = nv
+		}
+	}
+	for k, nv := range newMap {
+		if _, ok := oldMap[k]; !ok {
+			added[k] = nv
+		}
+	}
+	return added, removed, changed
+}
+
+// DirExists returns true if path exists and is a directory. Returns false, nil if
+// path does not exist or is not a directory; errors only for other failures, I.E.
+// permission denied.
+func DirExists(path string) (bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return fi.IsDir(), nil
+}
+
+// DirIsEmpty returns true if the directory exists and is empty.
+func DirIsEmpty(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		// Return false if the directory does not exist.
+		return false, fmt.Errorf("DirIsEmpty: path %s: %w", path, err)
+	}
+	defer f.Close()
+
+	// Readdirnames does NOT return "." and ".."; so a single file indicates the dir
+	// is not empty.
+	_, err = f.Readdirnames(1)
+	if err == io.EOF {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("DirIsEmpty: path %s: %w", path, err)
+	}
+
+	return false, nil
+}
+
+// DirIsEmptyIgnoring returns true if the directory exists and every entry in it has a
+// name in ignore; I.E. ignore []string{".DS_Store", ".gitkeep"}. Names are matched
+// exactly, there is no globbing, and every entry type, including symlinks, is
+// considered.
+func DirIsEmptyIgnoring(path string, ignore []string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("DirIsEmptyIgnoring: path %s: %w", path, err)
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return false, fmt.Errorf("DirIsEmptyIgnoring: path %s: %w", path, err)
+	}
+	for _, name := range names {
+		if !InStringSlice(name, ignore) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// EditOperations returns a minimal list of rune insert/delete/substitute operations
+// that transform a into b; the number of operations is the Levenshtein distance.
+// Operations are in order; see EditOp for the meaning of Position.
+func EditOperations(a, b string) []EditOp {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	// d[i][j] is the distance between ra[:i] and rb[:j].
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = d[i-1][j-1] + cost
+			if d[i-1][j]+1 < d[i][j] {
+				d[i][j] = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < d[i][j] {
+				d[i][j] = d[i][j-1] + 1
+			}
+		}
+	}
+
+	// Walk back from the end; while processing a[i:] the transformed string is
+	// b[:j]+a[i:], so the position of every operation is j.
+	ops := make([]EditOp, 0, d[len(ra)][len(rb)])
+	i, j := len(ra), len(rb)
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && ra[i-1] == rb[j-1] && d[i][j] == d[i-1][j-1]:
+			i--
+			j--
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+1:
+			ops = append(ops, EditOp{Type: EditSubstitute, Position: j - 1, Old: ra[i-1], New: rb[j-1]})
+			i--
+			j--
+		case i > 0 && d[i][j] == d[i-1][j]+1:
+			ops = append(ops, EditOp{Type: EditDelete, Position: j, Old: ra[i-1]})
+			i--
+		default:
+			ops = append(ops, EditOp{Type: EditInsert, Position: j - 1, New: rb[j-1]})
+			j--
+		}
+	}
+
+	// Reverse into forward order.
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+	return ops
+}
+
+// EnumsFromMapIntString creates lists of keys and values from a map[int]string.
+func EnumsFromMapIntString(m map[int]string) (keys []int, values []string) {
+	keys = make([]int, len(m))
+	i := 0
+	for k := range m {
+		keys[i] = k
+		i++
+	}
+	sort.Ints(keys)
+
+	for _, k := range keys {
+		values = append(values, m[k])
+	}
+
+	return keys, values
+}
+
+// EnumsFromMapStringInt creates lists of keys and values from a map[string]int.
+func EnumsFromMapStringInt(m map[string]int) (keys []string, values []int) {
+	keys = make([]string, len(m))
+	i := 0
+	for k := range m {
+		keys[i] = k
+		i++
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		values = append(values, m[k])
+	}
+
+	return keys, values
+}
+
+// Escape is the inverse of Unescape; backslash, newline, tab, and carriage return are
+// written as \\, \n, \t, and \r, other non-printable runes as \uNNNN, and control
+// characters, invalid UTF-8, and non-printable runes above U+FFFF as \xNN per byte.
+// Printable runes are unchanged, so Unescape(Escape(s)) == s for any s.
+func Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == utf8.RuneError && size == 1, r < 0x20, r == 0x7f, !unicode.IsPrint(r) && r > 0xffff:
+			for _, c := range []byte(s[i : i+size]) {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			}
+		case !unicode.IsPrint(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// ExpandEnv replaces ${NAME} in s with the value of NAME from lookup, and ${NAME:-default}
+// with default when NAME is unset or empty; os.LookupEnv is used when lookup is nil.
+// "$$" is written as "$", and a "$" not followed by "{" or "$" is unchanged.
+// Errors if a variable without a default is not defined, or a "${" is not terminated.
+func ExpandEnv(s string, lookup func(string) (string, bool)) (string, error) {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("ExpandEnv: unterminated variable at byte %d", i)
+			}
+			expr := s[i+2 : i+2+end]
+			name, def, hasDefault := strings.Cut(expr, ":-")
+			value, ok := lookup(name)
+			switch {
+			case hasDefault && value == "":
+				value = def
+			case !ok:
+				return "", fmt.Errorf("ExpandEnv: undefined variable %q", name)
+			}
+			b.WriteString(value)
+			i += 2 + end
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+// ExtractEmails returns the unique email addresses in text, in order of first occurrence.
+// Matching uses a simple regular expression (local@domain.tld) and is not RFC 5322
+// compliant; I.E. quoted local parts and IP address domains are not matched.
+func ExtractEmails(text string) []string {
+	return RemoveDuplicates(emailRegexp.FindAllString(text, -1))
+}
+
+// ExtractURLs returns the unique http, https, and ftp URLs in text, in order of first
+// occurrence. Matching uses a simple regular expression, not full RFC 3986 parsing;
+// a URL ends at whitespace, quotes, angle brackets, or parentheses, and trailing
+// sentence punctuation is removed, so URLs containing those characters are truncated.
+func ExtractURLs(text string) []string {
+	matches := urlRegexp.FindAllString(text, -1)
+	for i := range matches {
+		matches[i] = strings.TrimRight(matches[i], ".,;:!?")
+	}
+	return RemoveDuplicates(matches)
+}
+
+// FNV64Checksum provides the 64 bit FNV-1a hash of the input. It is fast, but not
+// suitable where collisions can be forced.
+func FNV64Checksum(input []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(input)
+	return h.Sum64()
+}
+
+// FNV64ChecksumString provides the FNV64Checksum of the input as 16 lower case hex digits.
+func FNV64ChecksumString(input []byte) string {
+	return fmt.Sprintf("%016x", FNV64Checksum(input))
+}
+
+// FileExists returns true if path exists and is not a directory. Returns false, nil if
+// path does not exist or is a directory; errors only for other failures, I.E.
+// permission denied.
+func FileExists(path string) (bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return !fi.IsDir(), nil
+}
+
+// FilterSlice returns, in order, the elements of in for which pred returns true.
+// The result is a non-nil empty slice when nothing matches.
+func FilterSlice[T any](in []T, pred func(T) bool) []T {
+	out := make([]T, 0)
+	for _, v := range in {
+		if pred(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// FindDuplicateJSONKeys returns the paths of keys that appear more than once within
+// the same object in data; encoding/json silently keeps the last value for such keys.
+// Paths are dot separated with array indexes, I.E. "a.0.b"; each path is returned once,
+// in the order found. The result is empty if there are no duplicates.
+// Errors if data is not valid JSON.
+func FindDuplicateJSONKeys(data []byte) ([]string, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	duplicates := make([]string, 0)
+	if err := findDuplicateJSONKeys(d, "", &duplicates); err != nil {
+		return nil, fmt.Errorf("FindDuplicateJSONKeys: %w", err)
+	}
+	if err := jsonEnd(d); err != nil {
+		return nil, fmt.Errorf("FindDuplicateJSONKeys: %w", err)
+	}
+	return duplicates, nil
+}
+
+// findDuplicateJSONKeys reads one value from d, recording duplicate key paths.
+func findDuplicateJSONKeys(d *json.Decoder, path string, duplicates *[]string) error {
+	tok, err := d.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		seen := map[string]struct{}{}
+		for d.More() {
+			keyTok, err := d.Token()
+			if err != nil {
+				return err
+			}
+			p := joinJSONPath(path, keyTok.(string))
+			if _, ok := seen[keyTok.(string)]; ok && !InStringSlice(p, *duplicates) {
+				*duplicates = append(*duplicates, p)
+			}
+			seen[keyTok.(string)] = struct{}{}
+			if err := findDuplicateJSONKeys(d, p, duplicates); err != nil {
+				return err
+			}
+		}
+		// Consume the closing delimiter.
+		_, err = d.Token()
+		return err
+	case json.Delim('['):
+		for i := 0; d.More(); i++ {
+			if err := findDuplicateJSONKeys(d, joinJSONPath(path, strconv.Itoa(i)), duplicates); err != nil {
+				return err
+			}
+		}
+		_, err = d.Token()
+		return err
+	}
+	return nil
+}
+
+// joinJSONPath appends elem, an object key or array index, to the dot separated JSON
+// path; "" is the path of the top-level value.
+func joinJSONPath(path, elem string) string {
+	if path == "" {
+		return elem
+	}
+	return path + "." + elem
+}
+
+// FlattenMap returns the leaf values of the nested input, I.E. decoded JSON, keyed by
+// their path of keys joined with sep; array elements use their index, so with sep "."
+// {"a":{"b":[1,{"c":2}]}} becomes {"a.b.0":1,"a.b.1.c":2}. Empty objects and arrays are
+// kept as values so UnflattenMap can restore them. Keys are joined as is, so keys that
+// contain sep make the result ambiguous.
+func FlattenMap(input map[string]interface{}, sep string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range input {
+		flattenValue(v, k, sep, out)
+	}
+	return out
+}
+
+// flattenValue adds v and its children at path to out for FlattenMap.
+func flattenValue(v interface{}, path, sep string, out map[string]interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			out[path] = t
+		}
+		for k, child := range t {
+			flattenValue(child, path+sep+k, sep, out)
+		}
+	case []interface{}:
+		if len(t) == 0 {
+			out[path] = t
+		}
+		for i, child := range t {
+			flattenValue(child, path+sep+strconv.Itoa(i), sep, out)
+		}
+	default:
+		out[path] = v
+	}
+}
+
+// FuzzyContains returns the element of list with the smallest Levenshtein distance
+// (in runes) to target, and true, if that distance is at most maxDistance; otherwise
+// returns "" and false. Ties go to the earliest element.
+func FuzzyContains(target string, list []string, maxDistance int) (string, bool) {
+	best, bestDistance := "", -1
+	for _, s := range list {
+		d := LevenshteinDistance(target, s)
+		if d <= maxDistance && (bestDistance < 0 || d < bestDistance) {
+			best, bestDistance = s, d
+			if d == 0 {
+				break
+			}
+		}
+	}
+	return best, bestDistance >= 0
+}
+
+// GapsBetween returns the inclusive [start,end] intervals not covered by intervals,
+// between the lowest start and highest end; see MergeIntervals.
+func GapsBetween(intervals [][2]int) [][2]int {
+	merged := MergeIntervals(intervals)
+	gaps := make([][2]int, 0)
+	for i := 1; i < len(merged); i++ {
+		gaps = append(gaps, [2]int{merged[i-1][1] + 1, merged[i][0] - 1})
+	}
+	return gaps
+}
+
+// HMACSHA256 provides the HMAC of message using SHA256 and key.
+func HMACSHA256(key, message []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// HMACSHA256Base64 provides a string with the HMACSHA256 of message in base64.
+func HMACSHA256Base64(key, message []byte) string {
+	return base64.StdEncoding.EncodeToString(HMACSHA256(key, message))
+}
+
+// HumanizeBytes returns n as a size in binary units with one decimal, I.E. "1.5 KiB" or
+// "-2.0 GiB"; sizes under 1 KiB are whole bytes, I.E. "0 B" or "512 B". See ParseBytes.
+func HumanizeBytes(n int64) string {
+	v := math.Abs(float64(n))
+	if v < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	u := 0
+	v /= 1024
+	// Move to the next unit when rounding would produce I.E. "1024.0 KiB".
+	for u < len(units)-1 && Round(v, 1) >= 1024 {
+		v /= 1024
+		u++
+	}
+	if n < 0 {
+		v = -v
+	}
+	return fmt.Sprintf("%.1f %s", Round(v, 1), units[u])
+}
+
+// HumanizeDuration returns d in a compact form without zero components; I.E. "2h3m"
+// rather than the "2h3m0s" of time.Duration.String. Durations of a day or more include
+// days, I.E. "1d2h", and durations of a minute or more are truncated to whole seconds.
+// Durations under a minute are as time.Duration.String, I.E. "0s", "450ms", or "1.5s".
+func HumanizeDuration(d time.Duration) string {
+	if d > -time.Minute && d < time.Minute {
+		return d.String()
+	}
+	sign := ""
+	// -math.MinInt64 overflows, so work with the negative value in uint64.
+	u := uint64(d)
+	if d < 0 {
+		sign = "-"
+		u = -u
+	}
+	var b strings.Builder
+	b.WriteString(sign)
+	for _, unit := range []struct {
+		suffix string
+		size   uint64
+	}{
+		{"d", uint64(24 * time.Hour)},
+		{"h", uint64(time.Hour)},
+		{"m", uint64(time.Minute)},
+		{"s", uint64(time.Second)},
+	} {
+		if n := u / unit.size; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, unit.suffix)
+			u %= unit.size
+		}
+	}
+	return b.String()
+}
+
+// InIntSlice checks if a int slice contains specific int.
+func InIntSlice(intToFind int, list []int) bool {
+	for _, v := range list {
+		if v == intToFind {
+			return true
+		}
+	}
+	return false
+}
+
+// InStringSlice checks if a string slice contains specific string.
+func InStringSlice(stringToFind string, list []string) bool {
+	for _, v := range list {
+		if v == stringToFind {
+			return true
+		}
+	}
+	return false
+}
+
+// InStringSliceFold checks if a string slice contains specific string, ignoring case
+// as defined by strings.EqualFold.
+func InStringSliceFold(stringToFind string, list []string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, stringToFind) {
+			return true
+		}
+	}
+	return false
+}
+
+// InStringSlicePtr checks if a string slice contains specific string.
+func InStringSlicePtr(stringToFind string, list []*string) bool {
+	values := make([]string, 0)
+	for _, v := range list {
+		values = append(values, *v)
+	}
+	return InStringSlice(stringToFind, values)
+}
+
+// IndentJSON returns input with each element on a new line, indented by indent per
+// level, via json.Indent. Errors if input is not valid JSON.
+func IndentJSON(input []byte, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, input, "", indent); err != nil {
+		return nil, fmt.Errorf("IndentJSON: invalid JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// IndexInIntSlice returns the index of the first instance of intToFind in list, or -1
+// if intToFind is not present.
+func IndexInIntSlice(intToFind int, list []int) int {
+	for i, v := range list {
+		if v == intToFind {
+			return i
+		}
+	}
+	return -1
+}
+
+// IndexInStringSlice returns the index of the first instance of stringToFind in list,
+// or -1 if stringToFind is not present.
+func IndexInStringSlice(stringToFind string, list []string) int {
+	for i, v := range list {
+		if v == stringToFind {
+			return i
+		}
+	}
+	return -1
+}
+
+// IntSliceInRange tests an integer slice to see if all values are in the inclusive
+// range [low, high]; returns true if yes, false otherwise.
+// filter is used to filter out values, as in MinMaxIntSlice.
+// Errors, returning false, if the input is an empty slice, or if all input values are
+// filtered out.
+func IntSliceInRange(in []int, low int, high int, filter map[int]string) (bool, error) {
+	min, max, err := MinMaxIntSlice(in, filter)
+	if err != nil {
+		return false, err
+	}
+	return min >= low && max <= high, nil
+}
+
+// IntSliceIsASCII tests an integer slice to see if all values are in the printable
+// ASCII range; returns true if yes, false otherwise.
+// filter is used to filter out values, like 0 that is
+// used for padding some string and is used for EOF; also used
+// to filter CR/LF/etc.
+// filter is a map mainly so text can be added to describe why a value is
+// filtered out, but the text is not required.
+// If the input contains ONLY filtered values, returns false
+// Erors if the input is an empty slice, or if all input values are filtered out.
+func IntSliceIsASCII(in []int, filter map[int]string) (bool, error) {
+	// Cannot include 127, which is DEL, or text compares on binary
+	// data will fail when a single field includes 0x7f
+	return IntSliceInRange(in, 32, 126, filter)
+}
+
+// IntSliceRemoveDuplicates removes duplicates from to integer slices; results may not be stable.
+func IntSliceRemoveDuplicates(in []int) []int {
+	return NewSet(in...).Slice()
+}
+
+// IntSliceRemoveDuplicatesStable removes duplicates from an integer slice, preserving
+// the order of first occurrence.
+func IntSliceRemoveDuplicatesStable(in []int) []int {
+	return RemoveDuplicates(in)
+}
+
+// InterleaveStrings alternates the runes of a and b, starting with a; I.E. "ace" and "bdf"
+// produce "abcdef". Errors if a and b do not have the same number of runes.
+func InterleaveStrings(a, b string) (string, error) {
+	ra := []rune(a)
+	rb := []rune(b)
+	if len(ra) != len(rb) {
+		return "", fmt.Errorf("InterleaveStrings: rune counts differ, %d != %d", len(ra), len(rb))
+	}
+
+	out := make([]rune, 0, len(ra)+len(rb))
+	for i := range ra {
+		out = append(out, ra[i], rb[i])
+	}
+	return string(out), nil
+}
+
+// InvertMap returns a map of the values of m to their keys. If values of m are not
+// unique, which of their keys is kept is not specified; see InvertMapToSlice.
+func InvertMap[K, V comparable](m map[K]V) map[V]K {
+	out := make(map[V]K, len(m))
+	for k, v := range m {
+		out[v] = k
+	}
+	return out
+}
+
+// InvertMapToSlice returns a map of the values of m to all of their keys, so values that
+// are not unique keep all keys. The order of the keys for each value is not specified.
+func InvertMapToSlice[K, V comparable](m map[K]V) map[V][]K {
+	out := make(map[V][]K, len(m))
+	for k, v := range m {
+		out[v] = append(out[v], k)
+	}
+	return out
+}
+
+// IsContiguous returns true if the deduplicated values of in form a gap-free run, I.E.
+// {3, 1, 2, 2} is contiguous and {1, 3} is not. An empty slice is contiguous.
+func IsContiguous(in []int) bool {
+	min, max, err := MinMaxIntSlice(in, nil)
+	if err != nil {
+		return true
+	}
+	// max-min can overflow an int; as uint64 the difference is correct since max >= min.
+	span := uint64(max) - uint64(min)
+	return span == uint64(len(NewSet(in...))-1)
+}
+
+// JSONChecksumIgnoring returns the hex SHA1Checksum of the CanonicalJSON form of data
+// after removing object keys named in ignoreKeys at any depth, so documents that differ
+// only in ignored keys, key order, or whitespace have the same checksum.
+// Errors if data is not valid JSON.
+func JSONChecksumIgnoring(data []byte, ignoreKeys []string) (string, error) {
+	v, err := decodeJSON(data)
+	if err != nil {
+		return "", fmt.Errorf("JSONChecksumIgnoring: %w", err)
+	}
+	ignore := make(map[string]struct{}, len(ignoreKeys))
+	for _, k := range ignoreKeys {
+		ignore[k] = struct{}{}
+	}
+	b, err := json.Marshal(removeJSONKeys(v, ignore))
+	if err != nil {
+		return "", fmt.Errorf("JSONChecksumIgnoring: %w", err)
+	}
+	canonical, err := CanonicalJSON(b)
+	if err != nil {
+		return "", fmt.Errorf("JSONChecksumIgnoring: %w", err)
+	}
+	return fmt.Sprintf("%x", SHA1Checksum(canonical)), nil
+}
+
+// removeJSONKeys deletes the keys in ignore from every object in the decoded JSON v,
+// in place, and returns v.
+func removeJSONKeys(v interface{}, ignore map[string]struct{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if _, ok := ignore[k]; ok {
+				delete(t, k)
+				continue
+			}
+			removeJSONKeys(child, ignore)
+		}
+	case []interface{}:
+		for _, child := range t {
+			removeJSONKeys(child, ignore)
+		}
+	}
+	return v
+}
+
+// JSONChecksumOf returns the hex SHA1Checksum of only the values of object keys named in
+// includeKeys, at any depth, so changes elsewhere in data do not change the checksum.
+// Each matching value is included with its path, dot separated with array indexes, in
+// CanonicalJSON form; so moving a value, or key order and whitespace changes within it,
+// are handled as for CanonicalJSON. Values nested within a matching value are part of it.
+// Errors if data is not valid JSON.
+func JSONChecksumOf(data []byte, includeKeys []string) (string, error) {
+	v, err := decodeJSON(data)
+	if err != nil {
+		return "", fmt.Errorf("JSONChecksumOf: %w", err)
+	}
+
+	selected := map[string]interface{}{}
+	var walk func(v interface{}, path string)
+	walk = func(v interface{}, path string) {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for k, child := range t {
+				if InStringSlice(k, includeKeys) {
+					selected[joinJSONPath(path, k)] = child
+				} else {
+					walk(child, joinJSONPath(path, k))
+				}
+			}
+		case []interface{}:
+			for i, child := range t {
+				walk(child, joinJSONPath(path, strconv.Itoa(i)))
+			}
+		}
+	}
+	walk(v, "")
+
+	b, err := json.Marshal(selected)
+	if err != nil {
+		return "", fmt.Errorf("JSONChecksumOf: %w", err)
+	}
+	canonical, err := CanonicalJSON(b)
+	if err != nil {
+		return "", fmt.Errorf("JSONChecksumOf: %w", err)
+	}
+	return fmt.Sprintf("%x", SHA1Checksum(canonical)), nil
+}
+
+// JSONStructureMatch compares the structure of JSON documents a and b, ignoring values;
+// objects must have the same keys, and values at the same path the same type (object,
+// array, string, number, bool, or null). Array elements are compared by index, and
+// elements beyond the end of the shorter array are compared with its first element, so
+// arrays of different lengths match if their elements do; an empty array matches any
+// array. Returns true if the structures match, otherwise false and the sorted paths
+// that differ; paths are dot separated with array indexes, I.E. "a.0.b", and "" is the
+// top-level value.
+// Errors if a or b is not valid JSON.
+func JSONStructureMatch(a, b []byte) (bool, []string, error) {
+	va, err := decodeJSON(a)
+	if err != nil {
+		return false, nil, fmt.Errorf("JSONStructureMatch: %w", err)
+	}
+	vb, err := decodeJSON(b)
+	if err != nil {
+		return false, nil, fmt.Errorf("JSONStructureMatch: %w", err)
+	}
+
+	diffs := []string{}
+	jsonStructureDiff(va, vb, "", &diffs)
+	diffs = RemoveDuplicates(diffs)
+	sort.Strings(diffs)
+	return len(diffs) == 0, diffs, nil
+}
+
+// jsonStructureDiff appends to diffs the paths at which the decoded JSON a and b differ
+// in structure.
+func jsonStructureDiff(a, b interface{}, path string, diffs *[]string) {
+	typeOf := func(v interface{}) string {
+		if v == nil {
+			return "null"
+		}
+		return fmt.Sprintf("%T", v)
+	}
+
+	if typeOf(a) != typeOf(b) {
+		*diffs = append(*diffs, path)
+		return
+	}
+	switch ta := a.(type) {
+	case map[string]interface{}:
+		tb := b.(map[string]interface{})
+		for k, v := range ta {
+			if bv, ok := tb[k]; ok {
+				jsonStructureDiff(v, bv, joinJSONPath(path, k), diffs)
+			} else {
+				*diffs = append(*diffs, joinJSONPath(path, k))
+			}
+		}
+		for k := range tb {
+			if _, ok := ta[k]; !ok {
+				*diffs = append(*diffs, joinJSONPath(path, k))
+			}
+		}
+	case []interface{}:
+		tb := b.([]interface{})
+		if len(ta) == 0 || len(tb) == 0 {
+			return
+		}
+		for i := 0; i < len(ta) || i < len(tb); i++ {
+			ea, eb := ta[0], tb[0]
+			if i < len(ta) {
+				ea = ta[i]
+			}
+			if i < len(tb) {
+				eb = tb[i]
+			}
+			jsonStructureDiff(ea, eb, joinJSONPath(path, strconv.Itoa(i)), diffs)
+		}
+	}
+}
+
+// LevenshteinDistance returns the minimum number of single rune insertions, deletions,
+// and substitutions needed to change a into b. See EditOperations for the operations.
+func LevenshteinDistance(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+	// Only the previous row of the distance matrix is needed.
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// MD5Checksum provides a []byte with the MD5 hash (checksum) for the input.
+func MD5Checksum(input []byte) [16]byte {
+	return md5.Sum(input)
+}
+
+// MD5ChecksumBase64 provides a string with the MD5 hash (checksum) in base64 for the input.
+func MD5ChecksumBase64(input []byte) string {
+	s := MD5Checksum(input)
+	return base64.StdEncoding.EncodeToString(s[:])
+}
+
+// MD5ChecksumBase64URL is MD5ChecksumBase64 using the URL and filename safe base64
+// alphabet, with padding; "-" and "_" replace "+" and "/".
+func MD5ChecksumBase64URL(input []byte) string {
+	s := MD5Checksum(input)
+	return base64.URLEncoding.EncodeToString(s[:])
+}
+
+// MapGetBool returns the value of key in m if it is a bool; ok is false if key is missing
+// or is another type.
+func MapGetBool(m map[string]interface{}, key string) (value bool, ok bool) {
+	value, ok = m[key].(bool)
+	return value, ok
+}
+
+// MapGetFloat returns the value of key in m as a float64, converting from any int type or
+// json.Number; ok is false if key is missing or is another type.
+func MapGetFloat(m map[string]interface{}, key string) (float64, bool) {
+	switch v := m[key].(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// MapGetInt returns the value of key in m as an int, converting from float64 (as
+// json.Unmarshal produces), other int types, or json.Number; ok is false if key is
+// missing, is another type, or is a number that is not a whole number in the range
+// of an int.
+func MapGetInt(m map[string]interface{}, key string) (int, bool) {
+	switch v := m[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		if v < int64(MinInt) || v > int64(MaxInt) {
+			return 0, false
+		}
+		return int(v), true
+	case int32:
+		return int(v), true
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 0); err == nil {
+			return int(i), true
+		}
+	}
+	f, ok := MapGetFloat(m, key)
+	// float64(MaxInt) rounds up to a power of 2, which is out of range.
+	if !ok || f != math.Trunc(f) || f < float64(MinInt) || f >= float64(MaxInt) {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// MapGetString returns the value of key in m if it is a string; ok is false if key is
+// missing or is another type.
+func MapGetString(m map[string]interface{}, key string) (value string, ok bool) {
+	value, ok = m[key].(string)
+	return value, ok
+}
+
+// MapSlice returns a slice with f applied to each element of in, in order.
+func MapSlice[T, U any](in []T, f func(T) U) []U {
+	out := make([]U, len(in))
+	for i, v := range in {
+		out[i] = f(v)
+	}
+	return out
+}
+
+// MergeIntervals merges inclusive [start,end] intervals that overlap or are adjacent
+// (I.E. [1,3] and [4,6]) into the minimal set of intervals, sorted by start.
+// Intervals with start > end are treated as [end,start]. The input is not modified.
+func MergeIntervals(intervals [][2]int) [][2]int {
+	sorted := make([][2]int, len(intervals))
+	for i, v := range intervals {
+		if v[0] > v[1] {
+			v[0], v[1] = v[1], v[0]
+		}
+		sorted[i] = v
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+
+	merged := make([][2]int, 0, len(sorted))
+	for _, v := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && (v[0] <= merged[last][1] || v[0]-merged[last][1] == 1) {
+			if v[1] > merged[last][1] {
+				merged[last][1] = v[1]
+			}
+			continue
+		}
+		merged = append(merged, v)
+	}
+	return merged
+}
+
+// MerkleProof returns the sibling hashes, from the leaf up, needed to compute the
+// MerkleRoot of blocks from the block at index; see VerifyMerkleProof.
+// Errors if blocks is empty or index is out of range.
+func MerkleProof(blocks [][]byte, index int) ([][]byte, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("MerkleProof: no blocks")
+	}
+	if index < 0 || index >= len(blocks) {
+		return nil, fmt.Errorf("MerkleProof: index %d out of range for %d blocks", index, len(blocks))
+	}
+	proof := [][]byte{}
+	level := merkleLeaves(blocks)
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling == len(level) {
+			sibling = index
+		}
+		proof = append(proof, level[sibling])
+		level = merkleLevel(level)
+		index /= 2
+	}
+	return proof, nil
+}
+
+// MerkleRoot returns the root of a binary hash tree of blocks using SHA256. Leaves are
+// the hash of 0x00 followed by the block, and nodes the hash of 0x01 followed by the
+// left and right child hashes, so a leaf cannot be passed off as a node. When a level
+// has an odd number of nodes, the last node is paired with itself.
+// Errors if blocks is empty.
+func MerkleRoot(blocks [][]byte) ([]byte, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("MerkleRoot: no blocks")
+	}
+	level := merkleLeaves(blocks)
+	for len(level) > 1 {
+		level = merkleLevel(level)
+	}
+	return level[0], nil
+}
+
+// merkleLeaves returns the leaf hashes of blocks.
+func merkleLeaves(blocks [][]byte) [][]byte {
+	leaves := make([][]byte, len(blocks))
+	for i, b := range blocks {
+		leaves[i] = merkleHash(0x00, b)
+	}
+	return leaves
+}
+
+// merkleLevel returns the parent hashes of level, pairing an odd last node with itself.
+func merkleLevel(level [][]byte) [][]byte {
+	parents := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		right := level[i]
+		if i+1 < len(level) {
+			right = level[i+1]
+		}
+		parents = append(parents, merkleHash(0x01, level[i], right))
+	}
+	return parents
+}
+
+// merkleHash returns the SHA256 of prefix followed by data.
+func merkleHash(prefix byte, data ...[]byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{prefix})
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// MinMaxIntSlice returns the max and min for an int slice.
+// Errors if the input is an empty slice, or if all input values are filtered out; the
+// error wraps ErrAllFiltered.
+// filter is used to filter out specific values; it is a map mainly
+// so text can be added to describe why a value is filtered out,
+// but the text is not required.
+func MinMaxIntSlice(in []int, filter map[int]string) (int, int, error) {
+	var max = MinInt
+	var min = MaxInt
+	var found = false
+	var err error
+	for _, value := range in {
+		// Skip values in the filter
+		if _, ok := filter[value]; ok {
+			continue
+		}
+		found = true
+		if max < value {
+			max = value
+		}
+		if min > value {
+			min = value
+		}
+	}
+
+	if !found {
+		err = Wrap(ErrAllFiltered, "MinMaxIntSlice")
+	}
+
+	return min, max, err
+}
+
+// MinifyJSON returns input with all insignificant whitespace removed, via json.Compact.
+// Object key order is preserved. Errors if input is not valid JSON.
+func MinifyJSON(input []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, input); err != nil {
+		return nil, fmt.Errorf("MinifyJSON: invalid JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// MissingFromRange returns, in ascending order, the values between the min and max of
+// in that are not in in; see MinMaxIntSlice. An empty slice returns an empty slice.
+// Time and memory grow with max-min, not len(in), so widely spread values, I.E.
+// {0, 1 << 40}, are very slow; use IsContiguous to only check for gaps.
+func MissingFromRange(in []int) []int {
+	missing := make([]int, 0)
+	min, max, err := MinMaxIntSlice(in, nil)
+	if err != nil {
+		return missing
+	}
+	present := make(map[int]struct{}, len(in))
+	for _, v := range in {
+		present[v] = struct{}{}
+	}
+	for v := min; v < max; v++ {
+		if _, ok := present[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
+// MultiReaderSep is io.MultiReader with sep read between each pair of readers; sep is
+// not read before the first reader or after the last.
+func MultiReaderSep(sep []byte, readers ...io.Reader) io.Reader {
+	all := make([]io.Reader, 0, 2*len(readers))
+	for i, r := range readers {
+		if i > 0 {
+			all = append(all, bytes.NewReader(sep))
+		}
+		all = append(all, r)
+	}
+	return io.MultiReader(all...)
+}
+
+// NaturalLess reports whether a sorts before b in natural order, where runs of ASCII
+// digits compare by numeric value and all other runes compare by value; I.E. "file2"
+// sorts before "file10". Numbers of any length are supported. Numbers equal except for
+// leading zeros sort the one with fewer zeros first, so the order is total.
+// Use with sort.Slice.
+func NaturalLess(a, b string) bool {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	// tie records the first difference in leading zeros, used if nothing else differs.
+	tie := 0
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			si, sj := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			na := strings.TrimLeft(a[si:i], "0")
+			nb := strings.TrimLeft(b[sj:j], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			if tie == 0 && i-si != j-sj {
+				tie = (i - si) - (j - sj)
+			}
+			continue
+		}
+
+		ra, sizeA := utf8.DecodeRuneInString(a[i:])
+		rb, sizeB := utf8.DecodeRuneInString(b[j:])
+		if ra != rb {
+			return ra < rb
+		}
+		i += sizeA
+		j += sizeB
+	}
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+	return tie < 0
+}
+
+// NormalizeUnicode normalizes s to the Unicode normalization form named by form; one of
+// "NFC", "NFD", "NFKC", or "NFKD" (case insensitive). Normalizing to the same form lets
+// strings that are encoded differently but are canonically equivalent, I.E. "é" as a
+// single rune or as "e" plus a combining accent, compare as equal.
+// Errors on an unknown form.
+func NormalizeUnicode(s string, form string) (string, error) {
+	var f norm.Form
+	switch strings.ToUpper(form) {
+	case "NFC":
+		f = norm.NFC
+	case "NFD":
+		f = norm.NFD
+	case "NFKC":
+		f = norm.NFKC
+	case "NFKD":
+		f = norm.NFKD
+	default:
+		return "", fmt.Errorf("NormalizeUnicode: unknown form %q", form)
+	}
+	return f.String(s), nil
+}
+
+// PadLeft returns s with pad prepended until it is width runes long; s is returned
+// unchanged if it already has at least width runes.
+func PadLeft(s string, width int, pad rune) string {
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	return strings.Repeat(string(pad), n) + s
+}
+
+// PadRight returns s with pad appended until it is width runes long; s is returned
+// unchanged if it already has at least width runes.
+func PadRight(s string, width int, pad rune) string {
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	return s + strings.Repeat(string(pad), n)
+}
+
+// ParseBytes parses a size with an optional unit into bytes, rounded to the nearest byte.
+// Units are case insensitive and may be decimal (KB, MB, GB, TB, PB, EB; powers of 1000),
+// binary (KiB, MiB, GiB, TiB, PiB, EiB; powers of 1024), or B; no unit is bytes.
+// Whitespace around the number and unit is ignored, I.E. " 1.5 KiB " is 1536.
+// Errors if the number or unit is not valid, or the result overflows an int64.
+func ParseBytes(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.' || r == '-' || r == '+')
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	number, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ParseBytes: invalid number in %q", s)
+	}
+
+	multipliers := map[string]float64{"": 1, "b": 1}
+	for p, prefix := range []string{"k", "m", "g", "t", "p", "e"} {
+		multipliers[prefix+"b"] = math.Pow(1000, float64(p+1))
+		multipliers[prefix+"ib"] = math.Pow(1024, float64(p+1))
+	}
+	m, ok := multipliers[unit]
+	if !ok {
+		return 0, fmt.Errorf("ParseBytes: invalid unit in %q", s)
+	}
+	v = math.Round(v * m)
+	// float64(math.MaxInt64) rounds up to 2^63, which is out of range.
+	if v >= float64(math.MaxInt64) || v < float64(math.MinInt64) {
+		return 0, fmt.Errorf("ParseBytes: %q overflows int64", s)
+	}
+	return int64(v), nil
+}
+
+// ParseDataURI parses a data URI of the form "data:[<mime>][;base64],<payload>".
+// The payload is base64 decoded when ";base64" is present, otherwise it is URL
+// (percent) decoded. mimeType includes any parameters, I.E. "text/plain;charset=utf-8",
+// and defaults to "text/plain" when omitted.
+func ParseDataURI(s string) (mimeType string, data []byte, err error) {
+	if !strings.HasPrefix(s, "data:") {
+		return "", nil, errors.New("ParseDataURI: missing data: scheme")
+	}
+	header, payload, found := strings.Cut(s[len("data:"):], ",")
+	if !found {
+		return "", nil, errors.New("ParseDataURI: missing ',' separator")
+	}
+
+	isBase64 := false
+	if strings.HasSuffix(header, ";base64") {
+		isBase64 = true
+		header = strings.TrimSuffix(header, ";base64")
+	}
+	mimeType = header
+	if mimeType == "" || strings.HasPrefix(mimeType, ";") {
+		mimeType = "text/plain" + mimeType
+	}
+
+	if isBase64 {
+		data, err = base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return "", nil, fmt.Errorf("ParseDataURI: %w", err)
+		}
+		return mimeType, data, nil
+	}
+
+	unescaped, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("ParseDataURI: %w", err)
+	}
+	return mimeType, []byte(unescaped), nil
+}
+
+// ParseINI parses an INI file into a map of section to key to value. Keys before any
+// "[section]" header are in the "" section. Lines starting with ";" or "#" are
+// comments, a key without "=" has an empty value, and keys, values, and section names
+// are trimmed of whitespace. If a key repeats within a section the last value is kept.
+// Errors on malformed lines; the error includes the line number.
+func ParseINI(r io.Reader) (map[string]map[string]string, error) {
+	out := map[string]map[string]string{}
+	section := ""
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == ';' || text[0] == '#' {
+			continue
+		}
+
+		if text[0] == '[' {
+			if text[len(text)-1] != ']' {
+				return nil, fmt.Errorf("ParseINI: malformed section header on line %d", line)
+			}
+			section = strings.TrimSpace(text[1 : len(text)-1])
+			if section == "" {
+				return nil, fmt.Errorf("ParseINI: empty section name on line %d", line)
+			}
+			if out[section] == nil {
+				out[section] = map[string]string{}
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("ParseINI: missing key on line %d", line)
+		}
+		if out[section] == nil {
+			out[section] = map[string]string{}
+		}
+		out[section][key] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ParseINI: %w", err)
+	}
+	return out, nil
+}
+
+// PercentChange returns the percent change from oldValue to newValue;
+// ((newValue-oldValue)/|oldValue|)*100. The absolute value of oldValue is used so the
+// sign always indicates the direction of the change.
+// Errors if oldValue is 0, as the change is undefined.
+func PercentChange(oldValue, newValue float64) (float64, error) {
+	if oldValue == 0 {
+		return 0, errors.New("PercentChange: old value is 0")
+	}
+	return (newValue - oldValue) / math.Abs(oldValue) * 100, nil
+}
+
+// PercentChangeInf is PercentChange without an error; when oldValue is 0 the result is
+// 0 if newValue is also 0, otherwise +Inf or -Inf in the direction of newValue.
+func PercentChangeInf(oldValue, newValue float64) float64 {
+	if oldValue == 0 {
+		switch {
+		case newValue > 0:
+			return math.Inf(1)
+		case newValue < 0:
+			return math.Inf(-1)
+		}
+		return 0
+	}
+	pc, _ := PercentChange(oldValue, newValue)
+	return pc
 }
 
 // PrettyJSON transforms JSON for more friendly screen output.
@@ -324,7 +2845,11 @@ func MinMaxIntSlice(in []int, filter map[int]string) (int, int, error) {
 //
 // into:
 // "SomeJSONField": [1,2,3,4],
+// String literals are left unchanged.
 func PrettyJSON(json []byte) []byte {
+	// Swap string literals for placeholders so their content is not transformed.
+	json, literals := prettyJSONProtectStrings(json)
+
 	// re1: remove all CRLF from lines that only have a number followed by
 	// comma. This gets rid of all CRLF, but leaves the initial CRLF
 	// after the opening "["
@@ -338,68 +2863,1236 @@ func PrettyJSON(json []byte) []byte {
 	re3 := regexp.MustCompile(`([0-9.])\s*?]`)
 	json = re3.ReplaceAll(json, []byte("$1]"))
 
-	// JSON converts actual \n to "\n"; undo that
-	re4 := regexp.MustCompile(`\n`)
-	json = re4.ReplaceAll(json, []byte("\n"))
+	// JSON converts actual \n to "\n"; undo that
+	re4 := regexp.MustCompile(`\n`)
+	json = re4.ReplaceAll(json, []byte("\n"))
+
+	// Remove trailing whitespace from any line so that output is
+	// compatible with Golang Examples.
+	re5 := regexp.MustCompile(`(?m)\s*?$`)
+	json = re5.ReplaceAll(json, []byte(""))
+
+	return prettyJSONPlaceholder.ReplaceAllFunc(json, func(m []byte) []byte {
+		i := 0
+		for _, c := range m[1 : len(m)-1] {
+			i = i*10 + int(c-'a')
+		}
+		if i >= len(literals) {
+			return m
+		}
+		return literals[i]
+	})
+}
+
+// prettyJSONProtectStrings returns json with each string literal, including its quotes,
+// replaced by a placeholder matching prettyJSONPlaceholder, and the replaced literals.
+// The placeholder contains no digits or whitespace so the PrettyJSON regexes never
+// match it. An unterminated literal extends to the end of json.
+func prettyJSONProtectStrings(json []byte) ([]byte, [][]byte) {
+	out := make([]byte, 0, len(json))
+	literals := [][]byte{}
+	for i := 0; i < len(json); i++ {
+		if json[i] != '"' {
+			out = append(out, json[i])
+			continue
+		}
+		end := i + 1
+		for ; end < len(json) && json[end] != '"'; end++ {
+			if json[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(json) {
+			end = len(json) - 1
+		}
+		out = append(out, 0)
+		for _, c := range strconv.Itoa(len(literals)) {
+			out = append(out, byte('a'+c-'0'))
+		}
+		out = append(out, 0)
+		literals = append(literals, json[i:end+1])
+		i = end
+	}
+	return out, literals
+}
+
+// ReadChecksumManifest reads a sha256sum compatible manifest, as written by
+// WriteChecksumManifest, and returns a map of filename to hex hash. Lines in binary
+// mode ("hash *filename") and blank lines are accepted.
+// Errors on malformed lines; the error includes the line number.
+func ReadChecksumManifest(r io.Reader) (map[string]string, error) {
+	out := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		sum, file, found := strings.Cut(text, " ")
+		if !found || sum == "" || len(file) < 2 || (file[0] != ' ' && file[0] != '*') {
+			return nil, fmt.Errorf("ReadChecksumManifest: malformed line %d", line)
+		}
+		if _, err := hex.DecodeString(sum); err != nil {
+			return nil, fmt.Errorf("ReadChecksumManifest: malformed hash on line %d: %w", line, err)
+		}
+		out[file[1:]] = strings.ToLower(sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ReadChecksumManifest: %w", err)
+	}
+	return out, nil
+}
+
+// Recase converts identifier, in any style SplitWords understands, to the target style.
+// In StyleCamel, StyleLowerCamel, and StyleTitle, known abbreviations (I.E. JSON, HTTP)
+// are kept in all caps, except as the first word of StyleLowerCamel.
+// An unknown target returns identifier unchanged.
+func Recase(identifier string, target Style) string {
+	words := SplitWords(identifier)
+	switch target {
+	case StyleSnake, StyleKebab:
+		for i := range words {
+			words[i] = strings.ToLower(words[i])
+		}
+		if target == StyleKebab {
+			return strings.Join(words, "-")
+		}
+		return strings.Join(words, "_")
+	case StyleScreamingSnake:
+		return strings.ToUpper(strings.Join(words, "_"))
+	case StyleCamel, StyleLowerCamel, StyleTitle:
+		for i := range words {
+			if i == 0 && target == StyleLowerCamel {
+				words[i] = strings.ToLower(words[i])
+				continue
+			}
+			words[i] = recaseCapitalize(words[i])
+		}
+		if target == StyleTitle {
+			return strings.Join(words, " ")
+		}
+		return strings.Join(words, "")
+	}
+	return identifier
+}
+
+// recaseCapitalize returns word with the first rune upper case and the rest lower case,
+// or all upper case for known abbreviations.
+func recaseCapitalize(word string) string {
+	if InStringSliceFold(word, abbreviations) {
+		return strings.ToUpper(word)
+	}
+	runes := []rune(strings.ToLower(word))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// RedactJSONKeys returns input with the value of every object key in keys, at any depth,
+// replaced by "[REDACTED]"; keys match exactly and case sensitively. Arrays and nested
+// objects are walked. The output is compact with object keys sorted, as json.Marshal.
+// Errors if input is not valid JSON.
+func RedactJSONKeys(input []byte, keys []string) ([]byte, error) {
+	return redactJSON("RedactJSONKeys", input, func(k string) bool { return InStringSlice(k, keys) })
+}
+
+// RedactJSONKeysFold is RedactJSONKeys with keys matched ignoring case, as defined by
+// strings.EqualFold.
+func RedactJSONKeysFold(input []byte, keys []string) ([]byte, error) {
+	return redactJSON("RedactJSONKeysFold", input, func(k string) bool { return InStringSliceFold(k, keys) })
+}
+
+// redactJSON implements RedactJSONKeys and RedactJSONKeysFold; match reports whether a
+// key is redacted, and name prefixes errors.
+func redactJSON(name string, input []byte, match func(string) bool) ([]byte, error) {
+	v, err := decodeJSON(input)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redactJSONValue(v, match)); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// redactJSONValue redacts the decoded JSON v in place and returns it.
+func redactJSONValue(v interface{}, match func(string) bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if match(k) {
+				t[k] = "[REDACTED]"
+			} else {
+				redactJSONValue(child, match)
+			}
+		}
+	case []interface{}:
+		for _, child := range t {
+			redactJSONValue(child, match)
+		}
+	}
+	return v
+}
+
+// ReduceSlice returns the result of calling f for each element of in, in order, with
+// the result of the previous call; init is used for the first call and returned when in
+// is empty.
+func ReduceSlice[T, U any](in []T, init U, f func(U, T) U) U {
+	acc := init
+	for _, v := range in {
+		acc = f(acc, v)
+	}
+	return acc
+}
+
+// RemoveDuplicates removes duplicates from a slice of any comparable type, preserving
+// the order of first occurrence. A nil input returns an empty, non-nil slice.
+func RemoveDuplicates[T comparable](in []T) []T {
+	seen := make(map[T]struct{}, len(in))
+	out := make([]T, 0, len(in))
+	for _, v := range in {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+	return out
+}
+
+// RenameWithMapping is UniqueStrings that also returns a map of each input index to the
+// unique name assigned to it, so references to the original entries can be updated.
+func RenameWithMapping(input []string, numberFormat string) ([]string, map[int]string) {
+	names, _ := UniqueStrings(input, numberFormat)
+	mapping := make(map[int]string, len(names))
+	for i, name := range names {
+		mapping[i] = name
+	}
+	return names, mapping
+}
+
+// RequestBearerToken returns the token from the first Bearer Authorization header of the
+// request; the scheme is case insensitive and whitespace around the scheme and token is
+// ignored. ok is false if there is no Bearer Authorization header, or it does not have
+// exactly one token.
+func RequestBearerToken(r *http.Request) (token string, ok bool) {
+	for _, v := range r.Header["Authorization"] {
+		fields := strings.Fields(v)
+		if len(fields) > 0 && strings.EqualFold(fields[0], "Bearer") {
+			if len(fields) != 2 {
+				return "", false
+			}
+			return fields[1], true
+		}
+	}
+	return "", false
+}
+
+// RequestQueryFirst returns the first value of the query parameter key of the request.
+// ok is false if key is not present, and true for a key present with an empty value,
+// I.E. "?key" or "?key=".
+func RequestQueryFirst(r *http.Request, key string) (value string, ok bool) {
+	values := RequestQueryMap(r)[key]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// RequestQueryMap returns the query parameters of the request, with all values of
+// repeated keys in order. Malformed parameters are ignored, see url.ParseQuery.
+func RequestQueryMap(r *http.Request) map[string][]string {
+	if r.URL == nil {
+		return map[string][]string{}
+	}
+	return r.URL.Query()
+}
+
+// RequestRemoteIP returns the IP address of the client of the request, without a port.
+// When trustForwarded is true, the leftmost address in the X-Forwarded-For header is
+// used if present; only trust the header when the request comes through a proxy that
+// sets it, as clients can send any value. Otherwise the host of r.RemoteAddr is used.
+func RequestRemoteIP(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			if ip := requestHost(strings.TrimSpace(first)); ip != "" {
+				return ip
+			}
+		}
+	}
+	return requestHost(r.RemoteAddr)
+}
+
+// requestHost returns addr without any port, and without brackets for IPv6 addresses;
+// I.E. "[::1]:8080" returns "::1".
+func requestHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// RequestUsername will return the username of the request when using basic or digest
+// authentication; if it can be determined.
+func RequestUsername(r *http.Request) string {
+	// r.Header["Authorization"] is a slice of strings. I.E.
+	// Basic authentication.
+	// "Authorization":[]string{"Basic YWRtaW46YWRtaW4="},
+	// Digest authentication
+	// r.Header["Authorization"] is a slice of strings. I.E.
+	// "Authorization":[]string{"Digest username=\"admin\", realm=\"Western Digital Corporation\", nonce=\"AHYBbBIPrPRMzsDo\",...}
+	// For Digest with userhash=true the username parameter is the hashed username, and
+	// that is what is returned.
+	for _, v := range r.Header["Authorization"] {
+		scheme, params, _ := strings.Cut(strings.TrimSpace(v), " ")
+		if strings.EqualFold(scheme, "Digest") {
+			p := parseAuthParams(params)
+			if u, ok := p["username"]; ok {
+				return u
+			}
+			// RFC 7616 extended notation; I.E. username*=UTF-8''J%C3%A4s%C3%B8n
+			if u, ok := p["username*"]; ok {
+				if _, encoded, found := strings.Cut(u, "''"); found {
+					if decoded, err := url.PathUnescape(encoded); err == nil {
+						return decoded
+					}
+				}
+			}
+
+			return ""
+		}
+
+		splits := strings.Split(v, ",")
+		for _, split := range splits {
+			if strings.Contains(split, "Basic ") {
+				u := strings.Split(split, " ")
+				if len(u) == 2 {
+					user, _ := base64.StdEncoding.DecodeString(u[1])
+					userSplit := strings.Split(string(user), ":")
+					return string(userSplit[0])
+				}
+
+				return ""
+			}
+		}
+	}
+
+	return ""
+}
+
+// parseAuthParams parses comma separated key=value authentication parameters, as used
+// by Digest authentication, into a map with lower case keys. Values may be tokens or
+// quoted strings; quoted strings may contain commas, "=", and backslash escapes.
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+
+		eq := strings.IndexAny(s, "=,")
+		if eq < 0 || s[eq] == ',' {
+			// A bare token without a value; skip it.
+			if eq < 0 {
+				return params
+			}
+			s = s[eq:]
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value strings.Builder
+		if strings.HasPrefix(s, `"`) {
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			// Skip past the closing quote, if any.
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value.WriteString(strings.TrimSpace(s[:end]))
+			s = s[end:]
+		}
+		params[key] = value.String()
+	}
+}
+
+// RequestUsernameBearer returns the value of claim, I.E. "sub" or "preferred_username",
+// from the JWT in the Bearer Authorization header of the request.
+// The JWT signature is NOT verified; only use this where the token has already been
+// validated, or for informational purposes such as logging.
+// Errors if there is no Bearer token, the token is malformed, or the claim is missing
+// or not a string.
+func RequestUsernameBearer(r *http.Request, claim string) (string, error) {
+	token, ok := RequestBearerToken(r)
+	if !ok {
+		return "", errors.New("RequestUsernameBearer: no Bearer token")
+	}
+
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return "", fmt.Errorf("RequestUsernameBearer: token has %d segments, expected 3", len(segments))
+	}
+	// JWT segments are base64url without padding, but tolerate padding.
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segments[1], "="))
+	if err != nil {
+		return "", fmt.Errorf("RequestUsernameBearer: decoding payload: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("RequestUsernameBearer: parsing payload: %w", err)
+	}
+	v, ok := claims[claim]
+	if !ok {
+		return "", fmt.Errorf("RequestUsernameBearer: claim %q not found", claim)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("RequestUsernameBearer: claim %q is not a string", claim)
+	}
+	return s, nil
+}
+
+// Retry calls fn until it returns nil, up to attempts times, waiting initialDelay after
+// the first failure and doubling the wait after each further failure; see RetryContext.
+// fn is called once if attempts <= 0. The error of the last attempt is returned wrapped
+// with the number of attempts.
+func Retry(attempts int, initialDelay time.Duration, fn func() error) error {
+	return retry(context.Background(), "Retry", attempts, initialDelay, fn)
+}
+
+// RetryContext is Retry, but stops waiting to retry when ctx is done and returns an error
+// wrapping ctx.Err(), which includes the message of the last error from fn. fn is not
+// called if ctx is already done.
+func RetryContext(ctx context.Context, attempts int, initialDelay time.Duration, fn func() error) error {
+	return retry(ctx, "RetryContext", attempts, initialDelay, fn)
+}
+
+// retry implements Retry and RetryContext; name is used in errors.
+func retry(ctx context.Context, name string, attempts int, delay time.Duration, fn func() error) error {
+	if attempts <= 0 {
+		attempts = 1
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			return fmt.Errorf("%s: %d attempts: %w", name, attempt, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s: %d attempts, last error: %v: %w", name, attempt, err, ctx.Err())
+		}
+		if delay < math.MaxInt64/2 {
+			delay *= 2
+		}
+	}
+}
+
+// ReverseString returns s with its runes in reverse order, so multibyte characters stay
+// valid UTF-8. It operates on runes, not grapheme clusters; a combining mark ends up
+// before, not after, the character it modified, and multi-rune emoji are reordered.
+func ReverseString(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+// Round a number to the nearest number of digits; I.E. 0 to round
+// to an integer, or -2 to round to hundreds.
+// x is returned unchanged if it is too large to be rounded to digits; I.E.
+// math.MaxFloat64 to 2 digits.
+func Round(x float64, digits int) float64 {
+	return roundPow10(x, digits, func(scaled float64) float64 {
+		return math.Floor(scaled + 0.5)
+	})
+}
+
+// RoundDown a number toward negative infinity to the number of digits; I.E.
+// RoundDown(-1.495, 2) is -1.50.
+func RoundDown(x float64, digits int) float64 {
+	return roundScaled(x, digits, math.Floor)
+}
+
+// RoundHalfEven a number to the nearest number of digits, with halves rounded to the
+// nearest even digit (banker's rounding); I.E. 1.485 rounds to 1.48 and 1.495 to 1.50
+// for 2 digits.
+func RoundHalfEven(x float64, digits int) float64 {
+	return roundScaled(x, digits, math.RoundToEven)
+}
+
+// RoundUp a number toward positive infinity to the number of digits; I.E.
+// RoundUp(1.491, 2) is 1.50.
+func RoundUp(x float64, digits int) float64 {
+	return roundScaled(x, digits, math.Ceil)
+}
+
+// roundPow10 scales x by 10^digits, applies round, then unscales. Negative digits
+// divide by 10^-digits so the result is an exact multiple of the power of 10.
+// x is returned unchanged when it is not finite, or when the scaled value is not finite
+// or too large to have a fractional part, as x is then already at full precision.
+func roundPow10(x float64, digits int, round func(float64) float64) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return x
+	}
+
+	var scaled float64
+	var pow float64
+	if digits < 0 {
+		pow = math.Pow10(-digits)
+		if math.IsInf(pow, 0) {
+			// Every finite x is closer to 0 than to 10^-digits.
+			return 0
+		}
+		scaled = x / pow
+	} else {
+		pow = math.Pow10(digits)
+		scaled = x * pow
+	}
+	if math.IsNaN(scaled) || math.IsInf(scaled, 0) || math.Abs(scaled) >= 1<<52 {
+		return x
+	}
+
+	if digits < 0 {
+		return round(scaled) * pow
+	}
+	return round(scaled) / pow
+}
+
+// roundScaled is roundPow10 with the scaled value first reduced to 15 significant
+// digits to remove binary representation error, so that I.E. 1.1*100
+// (110.00000000000001) rounds up to 110 and not 111, and 1.495*100 (149.49999999999997)
+// is treated as the half it represents.
+func roundScaled(x float64, digits int, round func(float64) float64) float64 {
+	return roundPow10(x, digits, func(scaled float64) float64 {
+		snapped, err := strconv.ParseFloat(strconv.FormatFloat(scaled, 'g', 15, 64), 64)
+		if err != nil {
+			snapped = scaled
+		}
+		return round(snapped)
+	})
+}
+
+// SHA1Checksum provides a []byte with the MD5 hash (checksum) for the input.
+func SHA1Checksum(input []byte) [20]byte {
+	return sha1.Sum(input)
+}
+
+// SHA1ChecksumBase64 provides a string with the MD5 hash (checksum) in base64 for the input.
+func SHA1ChecksumBase64(input []byte) string {
+	s := SHA1Checksum(input)
+	return base64.StdEncoding.EncodeToString(s[:])
+}
+
+// SHA1ChecksumBase64URL is SHA1ChecksumBase64 using the URL and filename safe base64
+// alphabet, with padding; "-" and "_" replace "+" and "/".
+func SHA1ChecksumBase64URL(input []byte) string {
+	s := SHA1Checksum(input)
+	return base64.URLEncoding.EncodeToString(s[:])
+}
+
+// SHA1ChecksumReaderContext provides the SHA1Checksum of everything read from r, reading
+// in chunks and checking ctx between chunks; if ctx is done, ctx.Err() is returned
+// without reading further. A Read that blocks is not interrupted, so the check happens
+// when it returns.
+func SHA1ChecksumReaderContext(ctx context.Context, r io.Reader) ([20]byte, error) {
+	var sum [20]byte
+	h := sha1.New()
+	buf := make([]byte, 32*1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return sum, err
+		}
+		n, err := r.Read(buf)
+		h.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return sum, fmt.Errorf("SHA1ChecksumReaderContext: %w", err)
+		}
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// SecureCompare returns true if a and b are equal, in time that depends only on their
+// lengths, using crypto/subtle.ConstantTimeCompare. It is for comparing secrets such as
+// API tokens or passwords, where == can leak how many leading bytes match; use == for
+// general equality.
+func SecureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// SideBySideDiff returns a and b side by side, one row per line, with the lines aligned
+// by their longest common subsequence. The gutter between the columns marks each row:
+// " " for equal lines, "|" for changed lines, "<" for lines only in a, and ">" for lines
+// only in b. Each column is width runes, and longer lines wrap onto continuation rows; a
+// width <= 0 uses the longest line so nothing wraps. Trailing spaces are removed from
+// each row.
+func SideBySideDiff(a, b string, width int) string {
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	}
+	la, lb := split(a), split(b)
+	if width <= 0 {
+		for _, l := range append(append([]string{}, la...), lb...) {
+			if n := utf8.RuneCountInString(l); n > width {
+				width = n
+			}
+		}
+		if width == 0 {
+			width = 1
+		}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of la[i:] and lb[j:].
+	lcs := make([][]int, len(la)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(lb)+1)
+	}
+	for i := len(la) - 1; i >= 0; i-- {
+		for j := len(lb) - 1; j >= 0; j-- {
+			if la[i] == lb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Lines between common lines are paired as changed, and the excess from either side
+	// is shown as only in a or b.
+	type row struct {
+		left, gutter, right string
+	}
+	var rows []row
+	var deleted, inserted []string
+	flush := func() {
+		for k := 0; k < len(deleted) || k < len(inserted); k++ {
+			switch {
+			case k >= len(inserted):
+				rows = append(rows, row{deleted[k], "<", ""})
+			case k >= len(deleted):
+				rows = append(rows, row{"", ">", inserted[k]})
+			default:
+				rows = append(rows, row{deleted[k], "|", inserted[k]})
+			}
+		}
+		deleted, inserted = nil, nil
+	}
+	i, j := 0, 0
+	for i < len(la) || j < len(lb) {
+		switch {
+		case i < len(la) && j < len(lb) && la[i] == lb[j]:
+			flush()
+			rows = append(rows, row{la[i], " ", lb[j]})
+			i++
+			j++
+		case j == len(lb) || (i < len(la) && lcs[i+1][j] >= lcs[i][j+1]):
+			deleted = append(deleted, la[i])
+			i++
+		default:
+			inserted = append(inserted, lb[j])
+			j++
+		}
+	}
+	flush()
+
+	wrap := func(s string) []string {
+		r := []rune(s)
+		lines := []string{}
+		for len(r) > width {
+			lines = append(lines, string(r[:width]))
+			r = r[width:]
+		}
+		return append(lines, string(r))
+	}
+	var out strings.Builder
+	for _, rw := range rows {
+		left, right := wrap(rw.left), wrap(rw.right)
+		gutter := rw.gutter
+		for n := 0; n < len(left) || n < len(right); n++ {
+			var l, r string
+			if n < len(left) {
+				l = left[n]
+			}
+			if n < len(right) {
+				r = right[n]
+			}
+			line := PadRight(l, width, ' ') + " " + gutter + " " + r
+			out.WriteString(strings.TrimRight(line, " ") + "\n")
+			gutter = " "
+		}
+	}
+	return out.String()
+}
+
+// SortMapByValueDesc creates lists of keys and values from a map[string]int, ordered by
+// descending value; keys with equal values are sorted by key so the order is deterministic.
+// An empty map returns empty lists.
+func SortMapByValueDesc(m map[string]int) (keys []string, values []int) {
+	keys = make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
 
-	// Remove trailing whitespace from any line so that output is
-	// compatible with Golang Examples.
-	re5 := regexp.MustCompile(`(?m)\s*?$`)
-	json = re5.ReplaceAll(json, []byte(""))
+	values = make([]int, len(keys))
+	for i, k := range keys {
+		values[i] = m[k]
+	}
+	return keys, values
+}
+
+// SplitWords splits an identifier in CamelCase, lowerCamelCase, snake_case,
+// kebab-case, or space or dot separated form into words. A run of capitals is kept as a
+// single word, so "HTTPServerID" splits into "HTTP", "Server", and "ID". Digits stay
+// with the preceding word.
+func SplitWords(identifier string) []string {
+	words := []string{}
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
 
-	return json
+	runes := []rune(identifier)
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == '.' || unicode.IsSpace(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// Split on lower to upper, or before the last capital of a run followed by lower.
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
 }
 
-// RequestUsername will return the username of the request when using basic or digest
-// authentication; if it can be determined.
-func RequestUsername(r *http.Request) string {
-	// r.Header["Authorization"] is a slice of strings. I.E.
-	// Basic authentication.
-	// "Authorization":[]string{"Basic YWRtaW46YWRtaW4="},
-	// Digest authentication
-	// r.Header["Authorization"] is a slice of strings. I.E.
-	// "Authorization":[]string{"Digest username=\"admin\", realm=\"Western Digital Corporation\", nonce=\"AHYBbBIPrPRMzsDo\",...}
-	for _, v := range r.Header["Authorization"] {
-		splits := strings.Split(v, ",")
-		for _, split := range splits {
-			if strings.Contains(split, "username") {
-				u := strings.Split(split, "=")
-				if len(u) == 2 {
-					return strings.Replace(u[1], `"`, ``, -1)
-				}
+// StringSliceRemoveEmpty returns a new slice with all "" elements removed. When trim is
+// true, elements that reduce to "" with strings.TrimSpace are also removed; retained
+// elements are never modified. The input is not modified.
+func StringSliceRemoveEmpty(in []string, trim bool) []string {
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		if v == "" || (trim && strings.TrimSpace(v) == "") {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
 
-				return ""
-			} else if strings.Contains(split, "Basic ") {
-				u := strings.Split(split, " ")
-				if len(u) == 2 {
-					user, _ := base64.StdEncoding.DecodeString(u[1])
-					userSplit := strings.Split(string(user), ":")
-					return string(userSplit[0])
+// StringToByteSlice is the inverse of ByteSliceToString; it parses whitespace separated
+// hex bytes, on any number of lines, into a byte slice. Bytes with a "0x" prefix, as
+// produced by ByteSliceToStringFormatted, are also accepted.
+// Errors if any value is not a two digit hex byte.
+func StringToByteSlice(in string) ([]byte, error) {
+	fields := strings.Fields(in)
+	out := make([]byte, 0, len(fields))
+	for _, f := range fields {
+		h := f
+		if strings.HasPrefix(h, "0x") || strings.HasPrefix(h, "0X") {
+			h = h[2:]
+		}
+		if len(h) != 2 {
+			return nil, fmt.Errorf("StringToByteSlice: malformed hex byte %q", f)
+		}
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, fmt.Errorf("StringToByteSlice: malformed hex byte %q", f)
+		}
+		out = append(out, b[0])
+	}
+	return out, nil
+}
+
+// StructsToCSV converts records, a slice of structs or struct pointers, to CSV with a
+// header row. Column names come from the csv tag, then the json tag, then the field
+// name; unexported fields and fields tagged "-" are skipped.
+// Fields must be strings, bools, numbers, encoding.TextMarshaler, or pointers to those;
+// nil pointers are written as "". Errors on any other field type, I.E. nested structs,
+// slices, and maps.
+func StructsToCSV(records interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(records)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("StructsToCSV: records must be a slice, not %T", records)
+	}
+	rt := rv.Type().Elem()
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("StructsToCSV: records must be a slice of structs, not %T", records)
+	}
+
+	// Determine the columns.
+	fields := make([]int, 0, rt.NumField())
+	header := make([]string, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		for _, tagName := range []string{"csv", "json"} {
+			if tag, ok := f.Tag.Lookup(tagName); ok {
+				tag, _, _ = strings.Cut(tag, ",")
+				if tag != "" {
+					name = tag
 				}
+				break
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		if !csvFieldSupported(f.Type) {
+			return nil, fmt.Errorf("StructsToCSV: unsupported type %s for field %s", f.Type, f.Name)
+		}
+		fields = append(fields, i)
+		header = append(header, name)
+	}
 
-				return ""
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("StructsToCSV: %w", err)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		record := rv.Index(i)
+		if record.Kind() == reflect.Ptr {
+			if record.IsNil() {
+				return nil, fmt.Errorf("StructsToCSV: nil record at index %d", i)
 			}
+			record = record.Elem()
 		}
+
+		row := make([]string, len(fields))
+		for j, fi := range fields {
+			s, err := csvFieldString(record.Field(fi))
+			if err != nil {
+				return nil, fmt.Errorf("StructsToCSV: record %d field %s: %w", i, header[j], err)
+			}
+			row[j] = s
+		}
+		if err := w.Write(row); err != nil {
+			return nil, fmt.Errorf("StructsToCSV: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("StructsToCSV: %w", err)
 	}
+	return buf.Bytes(), nil
+}
 
-	return ""
+// csvFieldSupported returns true if csvFieldString can convert a value of type t.
+func csvFieldSupported(t reflect.Type) bool {
+	if t.Implements(textMarshalerType) {
+		return true
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if t.Implements(textMarshalerType) {
+			return true
+		}
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
 }
 
-// Round a number to the nearest number of digits; I.E. 0 to round
-// to an integer.
-func Round(x float64, digits int) float64 {
-	return math.Floor(x*math.Pow10(digits)+0.5) / math.Pow10(digits)
+// csvFieldString converts a value of a type supported by csvFieldSupported to a string.
+func csvFieldString(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		if !v.Type().Implements(textMarshalerType) {
+			v = v.Elem()
+		}
+	}
+	if tm, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
 }
 
-// SHA1Checksum provides a []byte with the MD5 hash (checksum) for the input.
-func SHA1Checksum(input []byte) [20]byte {
-	return sha1.Sum(input)
+// ToUTF8 returns data as UTF-8 without a byte order mark. UTF-8 and UTF-16 (big or little
+// endian) are detected by byte order mark; without one, data that is valid UTF-8 is
+// UTF-8, and otherwise UTF-16 is detected by the zero high bytes of mostly ASCII text.
+// Errors if data is not valid in the detected encoding, or the result contains a NUL,
+// which is taken to mean data is binary.
+func ToUTF8(data []byte) ([]byte, error) {
+	var out []byte
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		out = append([]byte{}, data[3:]...)
+		if !utf8.Valid(out) {
+			err = errors.New("invalid UTF-8")
+		}
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		out, err = toUTF8FromUTF16(data[2:], binary.LittleEndian)
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		out, err = toUTF8FromUTF16(data[2:], binary.BigEndian)
+	case utf8.Valid(data) && bytes.IndexByte(data, 0) < 0:
+		out = append([]byte{}, data...)
+	default:
+		// For ASCII text, UTF-16 has a zero high byte in every code unit.
+		var zeroEven, zeroOdd int
+		for i, b := range data {
+			if b == 0 && i%2 == 0 {
+				zeroEven++
+			} else if b == 0 {
+				zeroOdd++
+			}
+		}
+		units := len(data) / 2
+		switch {
+		case zeroOdd*4 >= units && zeroEven*10 <= zeroOdd:
+			out, err = toUTF8FromUTF16(data, binary.LittleEndian)
+		case zeroEven*4 >= units && zeroOdd*10 <= zeroEven:
+			out, err = toUTF8FromUTF16(data, binary.BigEndian)
+		default:
+			err = errors.New("unknown encoding")
+		}
+	}
+	if err == nil && bytes.IndexByte(out, 0) >= 0 {
+		err = errors.New("data contains NUL")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ToUTF8: %w", err)
+	}
+	return out, nil
 }
 
-// SHA1ChecksumBase64 provides a string with the MD5 hash (checksum) in base64 for the input.
-func SHA1ChecksumBase64(input []byte) string {
-	s := SHA1Checksum(input)
-	return base64.StdEncoding.EncodeToString(s[:])
+// toUTF8FromUTF16 decodes UTF-16 data in the specified byte order to UTF-8; errors on an
+// odd length or unpaired surrogate.
+func toUTF8FromUTF16(data []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(data)%2 != 0 {
+		return nil, errors.New("odd length UTF-16")
+	}
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i += 2 {
+		r := rune(order.Uint16(data[i:]))
+		if utf16.IsSurrogate(r) {
+			if i+4 > len(data) {
+				return nil, fmt.Errorf("unpaired UTF-16 surrogate at byte %d", i)
+			}
+			r = utf16.DecodeRune(r, rune(order.Uint16(data[i+2:])))
+			if r == utf8.RuneError {
+				return nil, fmt.Errorf("unpaired UTF-16 surrogate at byte %d", i)
+			}
+			i += 2
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return out, nil
+}
+
+// TransformCSV reads CSV rows from r, calls transform on each, and writes the returned
+// rows to w. A nil row from transform drops that row. If keepHeader is true the first
+// row is written unchanged without calling transform.
+// Stops and errors on the first read, transform, or write error; errors include the
+// 1-based row number.
+func TransformCSV(r io.Reader, w io.Writer, transform func(row []string) ([]string, error), keepHeader bool) error {
+	cr := csv.NewReader(r)
+	cw := csv.NewWriter(w)
+	for rowNum := 1; ; rowNum++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("TransformCSV: %w", err)
+		}
+		if !(keepHeader && rowNum == 1) {
+			if row, err = transform(row); err != nil {
+				return fmt.Errorf("TransformCSV: row %d: %w", rowNum, err)
+			}
+			if row == nil {
+				continue
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("TransformCSV: row %d: %w", rowNum, err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("TransformCSV: %w", err)
+	}
+	return nil
+}
+
+// Truncate returns s unchanged if it has at most maxRunes runes, otherwise the first
+// runes of s followed by ellipsis, totaling maxRunes runes. Lengths are in runes, not
+// bytes, so multibyte characters are never split. If ellipsis has more than maxRunes
+// runes, the first maxRunes runes of ellipsis are returned.
+func Truncate(s string, maxRunes int, ellipsis string) string {
+	if maxRunes < 0 {
+		maxRunes = 0
+	}
+	if utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	e := []rune(ellipsis)
+	if len(e) >= maxRunes {
+		return string(e[:maxRunes])
+	}
+	return string([]rune(s)[:maxRunes-len(e)]) + ellipsis
+}
+
+// UUIDv4 returns a random RFC 4122 version 4 UUID, I.E.
+// "f47ac10b-58cc-4372-a567-0e02b2c3d479", generated with crypto/rand.
+// Errors if random data cannot be read.
+func UUIDv4() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", fmt.Errorf("UUIDv4: %w", err)
+	}
+	return uuidFormat(u, 4), nil
+}
+
+// UUIDv5 returns the RFC 4122 version 5 UUID for name in namespace, which is based on
+// SHA1Checksum, so the same namespace and name always give the same UUID.
+// namespace is a UUID in text form, I.E. UUIDNamespaceDNS. If namespace is not a valid
+// UUID its bytes are used as is, so the result is still deterministic, but will not
+// match other implementations.
+func UUIDv5(namespace, name string) string {
+	ns, err := hex.DecodeString(strings.ReplaceAll(namespace, "-", ""))
+	if err != nil || len(ns) != 16 {
+		ns = []byte(namespace)
+	}
+	sum := SHA1Checksum(append(ns, name...))
+	var u [16]byte
+	copy(u[:], sum[:16])
+	return uuidFormat(u, 5)
+}
+
+// uuidFormat sets the version and RFC 4122 variant bits of u and returns it in text form.
+func uuidFormat(u [16]byte, version byte) string {
+	u[6] = u[6]&0x0f | version<<4
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// Unescape replaces the escape sequences \n, \t, \r, \", \\, \xNN (a byte), and \uNNNN
+// (a rune) in s with the characters they represent; other characters are unchanged.
+// Errors on an unknown or incomplete escape sequence; the error includes the byte offset.
+func Unescape(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 >= len(s) {
+			return "", fmt.Errorf("Unescape: trailing backslash at byte %d", i)
+		}
+		switch c := s[i+1]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(c)
+		case 'x', 'u':
+			digits := 2
+			if c == 'u' {
+				digits = 4
+			}
+			if i+2+digits > len(s) {
+				return "", fmt.Errorf("Unescape: incomplete escape sequence %q at byte %d", s[i:], i)
+			}
+			v, err := strconv.ParseUint(s[i+2:i+2+digits], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("Unescape: invalid escape sequence %q at byte %d", s[i:i+2+digits], i)
+			}
+			if c == 'x' {
+				b.WriteByte(byte(v))
+			} else {
+				b.WriteRune(rune(v))
+			}
+			i += digits
+		default:
+			return "", fmt.Errorf("Unescape: invalid escape sequence %q at byte %d", s[i:i+2], i)
+		}
+		i++
+	}
+	return b.String(), nil
+}
+
+// UnflattenMap is the inverse of FlattenMap; keys of input are split on sep to rebuild
+// the nested maps. A nested map whose keys are exactly "0" to "n-1" becomes an array, so
+// nested objects with only such keys cannot be restored; the top level is always a map.
+// Values in input are not changed. Empty maps and arrays in input are kept, and merged
+// with any other keys under the same path.
+// Errors if sep is empty, or on conflicting keys, I.E. "a" with a value that is not an
+// empty map and "a.b".
+func UnflattenMap(input map[string]interface{}, sep string) (map[string]interface{}, error) {
+	if sep == "" {
+		return nil, errors.New("UnflattenMap: empty separator")
+	}
+	isEmptyContainer := func(v interface{}) bool {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			return len(t) == 0
+		case []interface{}:
+			return len(t) == 0
+		}
+		return false
+	}
+
+	// Process keys in order so a key is always seen before keys it is a prefix of.
+	keys := make([]string, 0, len(input))
+	for k := range input {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := map[string]interface{}{}
+	// leaves holds the keys whose values came from input; a leaf's path is its key.
+	leaves := map[string]bool{}
+	for _, k := range keys {
+		parts := strings.Split(k, sep)
+		m := out
+		for i, part := range parts[:len(parts)-1] {
+			path := strings.Join(parts[:i+1], sep)
+			child, ok := m[part]
+			if !ok || !leaves[path] {
+				if !ok {
+					child = map[string]interface{}{}
+					m[part] = child
+				}
+				m = child.(map[string]interface{})
+				continue
+			}
+			// An empty map or array from input is merged with the keys below it.
+			if !isEmptyContainer(child) {
+				return nil, fmt.Errorf("UnflattenMap: key %q conflicts with key %q", k, path)
+			}
+			delete(leaves, path)
+			next := map[string]interface{}{}
+			m[part] = next
+			m = next
+		}
+
+		last := parts[len(parts)-1]
+		if _, ok := m[last]; ok {
+			if isEmptyContainer(input[k]) {
+				continue
+			}
+			return nil, fmt.Errorf("UnflattenMap: key %q conflicts with keys prefixed by it", k)
+		}
+		m[last] = input[k]
+		leaves[k] = true
+	}
+	// The top level is always a map, even if its keys are "0" to "n-1".
+	for k, child := range out {
+		out[k] = unflattenArrays(child, k, sep, leaves)
+	}
+	return out, nil
+}
+
+// unflattenArrays converts, depth first, maps built by UnflattenMap whose keys are
+// exactly "0" to "n-1" into arrays; path is the key of v, and values from input, which
+// are at paths in leaves, are not changed.
+func unflattenArrays(v interface{}, path, sep string, leaves map[string]bool) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok || leaves[path] {
+		return v
+	}
+	for k, child := range m {
+		m[k] = unflattenArrays(child, path+sep+k, sep, leaves)
+	}
+	if len(m) == 0 {
+		return m
+	}
+	arr := make([]interface{}, len(m))
+	for i := range arr {
+		child, ok := m[strconv.Itoa(i)]
+		if !ok {
+			return m
+		}
+		arr[i] = child
+	}
+	return arr
 }
 
 // UniqueStrings creates a list of unique strings from the input.
@@ -409,32 +4102,99 @@ func SHA1ChecksumBase64(input []byte) string {
 // occurred.
 // If any inputs reduce to "" with strings.TrimSpace, they are replaced with "_"
 // and then above appends are added to create unique names. This prevents a
-// entirely blank string being returned.
+// entirely blank string being returned. input is not modified.
 func UniqueStrings(input []string, numberFormat string) ([]string, bool) {
+	return UniqueStringsExcluding(input, nil, numberFormat)
+}
+
+// UniqueStringsExcluding is UniqueStrings where no returned string is in existing; an
+// input equal to an existing name is numbered as if that name occurred earlier in input,
+// and numbers that would produce an existing name are skipped.
+// If numberFormat does not give different names for different numbers, I.E. "%[1]s"
+// which ignores the number, "%s_%d" is used instead so unique names can be created.
+func UniqueStringsExcluding(input []string, existing map[string]struct{}, numberFormat string) ([]string, bool) {
+	const fallbackFormat = "%s_%d"
+	if fmt.Sprintf(numberFormat, "_", 1) == fmt.Sprintf(numberFormat, "_", 2) {
+		numberFormat = fallbackFormat
+	}
 	m := map[string]int{}
+	for name := range existing {
+		m[name] = 1
+	}
 	duplicates := false
 	ret := make([]string, len(input))
 	for i := range input {
-		// Dont allow an empty string to be returned.
-		if strings.TrimSpace(input[i]) == "" {
-			input[i] = "_"
+		// Dont allow an empty string to be returned; input is not modified.
+		name := input[i]
+		if strings.TrimSpace(name) == "" {
+			name = "_"
 		}
-		if _, ok := m[input[i]]; ok {
-			m[input[i]]++
-			ret[i] = fmt.Sprintf(numberFormat, input[i], m[input[i]])
+		if _, ok := m[name]; ok {
+			// Distinct numbered names cannot all be in existing, so more tries than
+			// len(existing) means numberFormat repeats names for some numbers.
+			for tries := 0; ; tries++ {
+				if tries > len(existing) && numberFormat != fallbackFormat {
+					numberFormat = fallbackFormat
+					tries = 0
+				}
+				m[name]++
+				ret[i] = fmt.Sprintf(numberFormat, name, m[name])
+				if _, reserved := existing[ret[i]]; !reserved {
+					break
+				}
+			}
 			duplicates = true
 		} else {
-			m[input[i]] = 1
-			ret[i] = input[i]
+			m[name] = 1
+			ret[i] = name
 		}
 	}
 
 	return ret, duplicates
 }
 
-// VerifyMapKeysStringString verifies an input map contains required keys;
+// ValidateKeys verifies obj, I.E. a decoded JSON object, contains every key in required
+// and no keys other than those in required and optional.
+// Errors listing the missing keys, in the order of required, and the unexpected keys,
+// sorted.
+func ValidateKeys(obj map[string]interface{}, required, optional []string) error {
+	missing := VerifyMapKeysMissing(required, obj)
+
+	allowed := make(map[string]struct{}, len(required)+len(optional))
+	for _, k := range append(append([]string{}, required...), optional...) {
+		allowed[k] = struct{}{}
+	}
+	unexpected := []string{}
+	for k := range obj {
+		if _, ok := allowed[k]; !ok {
+			unexpected = append(unexpected, k)
+		}
+	}
+	sort.Strings(unexpected)
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing required keys %q", missing))
+	}
+	if len(unexpected) > 0 {
+		problems = append(problems, fmt.Sprintf("unexpected keys %q", unexpected))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("ValidateKeys: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// VerifyHMACSHA256 returns true if expectedMAC is the HMACSHA256 of message with key.
+// The comparison is constant time (hmac.Equal), so timing does not reveal how much of
+// expectedMAC is correct; only its length, which is not secret, affects timing.
+func VerifyHMACSHA256(key, message, expectedMAC []byte) bool {
+	return hmac.Equal(HMACSHA256(key, message), expectedMAC)
+}
+
+// VerifyMapKeys verifies an input map contains required keys;
 // true is all keys found, false otherwise.
-func VerifyMapKeysStringString(keys []string, testMap map[string]string) bool {
+func VerifyMapKeys[K comparable, V any](keys []K, testMap map[K]V) bool {
 	allKeysFound := true
 	for i := range keys {
 		if _, ok := testMap[keys[i]]; !ok {
@@ -444,3 +4204,180 @@ func VerifyMapKeysStringString(keys []string, testMap map[string]string) bool {
 	}
 	return allKeysFound
 }
+
+// VerifyMapKeysMissing returns the required keys that are not in the input map, in the
+// order of keys; the result is empty if all keys are found.
+func VerifyMapKeysMissing[K comparable, V any](keys []K, testMap map[K]V) []K {
+	missing := make([]K, 0)
+	for i := range keys {
+		if _, ok := testMap[keys[i]]; !ok {
+			missing = append(missing, keys[i])
+		}
+	}
+	return missing
+}
+
+// VerifyMapKeysStringString verifies an input map contains required keys;
+// true is all keys found, false otherwise.
+func VerifyMapKeysStringString(keys []string, testMap map[string]string) bool {
+	return VerifyMapKeys(keys, testMap)
+}
+
+// VerifyMerkleProof returns true if block, at index of count blocks, with proof from
+// MerkleProof, produces root from MerkleRoot. The proof must have exactly one hash per
+// level of the tree.
+func VerifyMerkleProof(block []byte, index, count int, proof [][]byte, root []byte) bool {
+	if index < 0 || index >= count {
+		return false
+	}
+	depth := 0
+	for n := count; n > 1; n = (n + 1) / 2 {
+		depth++
+	}
+	if len(proof) != depth {
+		return false
+	}
+	h := merkleHash(0x00, block)
+	for _, sibling := range proof {
+		if index%2 == 0 {
+			h = merkleHash(0x01, h, sibling)
+		} else {
+			h = merkleHash(0x01, sibling, h)
+		}
+		index /= 2
+	}
+	return bytes.Equal(h, root)
+}
+
+// WeightedMovingAverage returns the average of each window of len(weights) consecutive
+// values of in, weighted by weights; weights[0] applies to the oldest value of the window
+// and the last weight to the newest. Weights are normalized to sum to 1, so the result
+// has len(in)-len(weights)+1 values.
+// Errors if weights is empty, longer than in, or sums to 0.
+func WeightedMovingAverage(in []float64, weights []float64) ([]float64, error) {
+	if len(weights) == 0 {
+		return nil, errors.New("WeightedMovingAverage: empty weights")
+	}
+	if len(weights) > len(in) {
+		return nil, fmt.Errorf("WeightedMovingAverage: %d weights is more than %d values",
+			len(weights), len(in))
+	}
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return nil, errors.New("WeightedMovingAverage: weights sum to 0")
+	}
+
+	out := make([]float64, len(in)-len(weights)+1)
+	for i := range out {
+		var sum float64
+		for j, w := range weights {
+			sum += in[i+j] * w
+		}
+		out[i] = sum / total
+	}
+	return out, nil
+}
+
+// WindowChecksums returns the CRC32Checksum of each of the windowSize byte windows of
+// data starting at offsets 0, step, 2*step, and so on; checksum i covers
+// data[i*step : i*step+windowSize]. Only full windows are included, so trailing bytes
+// that do not fill a window are not covered. When step < windowSize windows overlap,
+// when step == windowSize they are adjacent, and when step > windowSize bytes between
+// windows are skipped.
+// Returns nil if windowSize or step is not positive.
+func WindowChecksums(data []byte, windowSize, step int) []uint32 {
+	if windowSize <= 0 || step <= 0 {
+		return nil
+	}
+	out := []uint32{}
+	for start := 0; start+windowSize <= len(data); start += step {
+		out = append(out, CRC32Checksum(data[start:start+windowSize]))
+	}
+	return out
+}
+
+// Wrap returns err with msg as context, I.E. the function name, in the form
+// "msg: err"; errors.Is and errors.As see through to err. Returns nil if err is nil.
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// WriteChecksumManifest writes a sha256sum compatible manifest to w; one line of
+// "hash  filename" per file. algo is one of "md5", "sha1", or "sha256".
+// Errors if algo is not supported or any file cannot be read; the error includes the
+// file name.
+func WriteChecksumManifest(files []string, algo string, w io.Writer) error {
+	for _, file := range files {
+		h, err := newChecksumHash(algo)
+		if err != nil {
+			return fmt.Errorf("WriteChecksumManifest: %w", err)
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("WriteChecksumManifest: %w", err)
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("WriteChecksumManifest: %s: %w", file, err)
+		}
+
+		if _, err := fmt.Fprintf(w, "%x  %s\n", h.Sum(nil), file); err != nil {
+			return fmt.Errorf("WriteChecksumManifest: %w", err)
+		}
+	}
+	return nil
+}
+
+// WriteFileAtomic writes data to path like os.WriteFile, but writes to a temporary file
+// in the same directory and renames it over path, so path is never partially written;
+// rename is atomic on POSIX file systems. The file has mode perm, which is not modified
+// by the umask. The temporary file is removed on failure.
+// Errors if the directory of path does not exist.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("WriteFileAtomic: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			err = fmt.Errorf("WriteFileAtomic: %w", err)
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// newChecksumHash returns a new hash.Hash for the named algorithm.
+func newChecksumHash(algo string) (hash.Hash, error) {
+	switch strings.ToLower(algo) {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm: %s", algo)
+}
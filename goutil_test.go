@@ -1,16 +1,33 @@
 package goutil
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/user"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 func init() {
@@ -20,6 +37,73 @@ var (
 	reqUser string
 )
 
+func ExampleBase64DecodeAny() {
+	for _, s := range []string{
+		"aGk/Pz4+",  // StdEncoding
+		"aGk_Pz4-",  // URLEncoding
+		"aGk/Pz4",   // RawStdEncoding
+		"aGk_Pz4",   // RawURLEncoding
+		"aGk/Pz4=",  // StdEncoding, padded
+		"aGk_Pz4==", // invalid padding
+		"",
+	} {
+		b, err := Base64DecodeAny(s)
+		fmt.Printf("%q %v\n", b, err)
+	}
+	// Output:
+	// "hi??>>" <nil>
+	// "hi??>>" <nil>
+	// "hi??>" <nil>
+	// "hi??>" <nil>
+	// "hi??>" <nil>
+	// "" Base64DecodeAny: illegal base64 data at input byte 7
+	// "" <nil>
+}
+
+func ExampleBlockDiff() {
+	oldData := []byte("aaaabbbbccccdddd")
+	newData := []byte("aaaabbXbccccdddd")
+	fmt.Println(BlockDiff(oldData, newData, 4))
+	fmt.Println(BlockDiff(oldData, oldData, 4))
+	fmt.Println(BlockDiff(oldData, append(newData, "ee"...), 4))
+
+	// Output:
+	// [1]
+	// []
+	// [1 4]
+}
+
+func ExampleByteSliceIsASCII_true() {
+	someBytes := []byte{32, 33, 125, 126}
+	filter := map[byte]string{}
+	ascii, _ := ByteSliceIsASCII(someBytes, filter)
+	fmt.Printf("ByteSliceIsASCII:%v", ascii)
+	// Output:
+	// ByteSliceIsASCII:true
+}
+
+func ExampleByteSliceIsASCII_false() {
+	someBytes := []byte{10, 1, 200, 255, 50}
+	filter := map[byte]string{}
+	ascii, _ := ByteSliceIsASCII(someBytes, filter)
+	fmt.Printf("ByteSliceIsASCII:%v\n", ascii)
+	// Filter CR/LF so text lines are ASCII.
+	ascii, _ = ByteSliceIsASCII([]byte("line\r\n"), map[byte]string{'\r': "CR", '\n': "LF"})
+	fmt.Printf("ByteSliceIsASCII:%v", ascii)
+	// Output:
+	// ByteSliceIsASCII:false
+	// ByteSliceIsASCII:true
+}
+
+func ExampleByteSliceIsASCII_allValuesFiltered() {
+	someBytes := []byte{32, 33, 126, 127}
+	filter := map[byte]string{32: "", 33: "", 126: "", 127: ""}
+	_, err := ByteSliceIsASCII(someBytes, filter)
+	fmt.Printf("Error:%v", err)
+	// Output:
+	// Error:ByteSliceIsASCII: all inputs were filtered
+}
+
 func ExampleByteSliceToString() {
 	fmt.Print(ByteSliceToString([]byte{}, 3))
 	fmt.Print(ByteSliceToString([]byte{0}, 3))
@@ -36,6 +120,183 @@ func ExampleByteSliceToString() {
 	// 03 04 05
 }
 
+func ExampleByteSliceToStringSep() {
+	in := []byte{0, 1, 2, 3, 4, 5, 6}
+	fmt.Printf("%q\n", ByteSliceToStringSep(in, 3, "\r\n", true))
+	fmt.Printf("%q\n", ByteSliceToStringSep(in, 3, "|", false))
+	fmt.Printf("%q\n", ByteSliceToStringSep(in, 0, "\n", true))
+	fmt.Printf("%q\n", ByteSliceToStringSep([]byte{}, 3, "\n", true))
+	// Output is unchanged from ByteSliceToString when sep is "\n" with a trailing sep.
+	fmt.Println(ByteSliceToStringSep(in, 3, "\n", true) == "00 01 02\n03 04 05\n06\n")
+
+	// Output:
+	// "00 01 02\r\n03 04 05\r\n06\r\n"
+	// "00 01 02|03 04 05|06"
+	// "00 01 02 03 04 05 06\n"
+	// ""
+	// true
+}
+
+func ExampleByteSliceToStringFormatted() {
+	in := []byte{0x0a, 0xff}
+	fmt.Print(ByteSliceToStringFormatted(in, 2, false, false))
+	fmt.Print(ByteSliceToStringFormatted(in, 2, false, true))
+	fmt.Print(ByteSliceToStringFormatted(in, 2, true, false))
+	fmt.Print(ByteSliceToStringFormatted(in, 2, true, true))
+	fmt.Print(ByteSliceToStringFormatted([]byte{0x0a, 0xff, 0x01}, 2, true, true))
+	fmt.Printf("%q\n", ByteSliceToStringFormatted([]byte{}, 2, true, true))
+
+	// Output:
+	// 0a ff
+	// 0A FF
+	// 0x0a 0xff
+	// 0x0A 0xFF
+	// 0x0A 0xFF
+	// 0x01
+	// ""
+}
+
+func ExampleCRC32Checksum() {
+	fmt.Printf("%d 0x%08x\n", CRC32Checksum([]byte("admin")), CRC32Checksum([]byte("admin")))
+	fmt.Println(CRC32Checksum(nil))
+	// Output:
+	// 2282622326 0x880e0d76
+	// 0
+}
+
+func ExampleCRC32ChecksumString() {
+	fmt.Println(CRC32ChecksumString([]byte("admin")))
+	fmt.Println(CRC32ChecksumString([]byte("a")))
+	// Output:
+	// 880e0d76
+	// e8b7be43
+}
+
+func ExampleCacheKey() {
+	k1 := CacheKey("user", 42, 1.5, map[string]interface{}{"b": 2, "a": []int{1, 2}}, nil, true)
+	k2 := CacheKey("user", 42, 1.5, map[string]interface{}{"a": []int{1, 2}, "b": 2}, nil, true)
+	fmt.Println(len(k1), k1 == k2)
+
+	// Numbers compare by value, but types that encode differently do not collide.
+	fmt.Println(CacheKey(1) == CacheKey(1.0), CacheKey(1) == CacheKey("1"))
+	// Part boundaries are preserved.
+	fmt.Println(CacheKey("a", "bc") == CacheKey("ab", "c"))
+	// Unsupported types do not panic.
+	fmt.Println(CacheKey(make(chan int)) != "")
+
+	// Output:
+	// 40 true
+	// true false
+	// false
+	// true
+}
+
+func ExampleCanonicalJSON() {
+	b, _ := CanonicalJSON([]byte(`{ "b": [1, 2.50, 1e3],
+		"a": {"z": null, "y": "text"} }`))
+	fmt.Println(string(b))
+
+	_, err := CanonicalJSON([]byte(`{"a":1} {"b":2}`))
+	fmt.Println(err)
+
+	// Output:
+	// {"a":{"y":"text","z":null},"b":[1,2.50,1e3]}
+	// CanonicalJSON: invalid data after top-level value
+}
+
+func ExampleCanonicalizeHeaders() {
+	h := CanonicalizeHeaders(map[string][]string{
+		"content-type":    {"application/json"},
+		"X-REQUEST-ID":    {"b"},
+		"x-request-id":    {"a"},
+		"Accept-Encoding": {"gzip"},
+	})
+	keys := []string{}
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Println(k, h[k])
+	}
+
+	// Output:
+	// Accept-Encoding [gzip]
+	// Content-Type [application/json]
+	// X-Request-Id [b a]
+}
+
+func ExampleChunkSlice() {
+	fmt.Println(ChunkSlice([]string{"a", "b", "c", "d", "e", "f"}, 3))
+	fmt.Println(ChunkSlice([]int{1, 2, 3, 4, 5}, 2))
+	fmt.Println(ChunkSlice([]int{1, 2, 3}, 0))
+	fmt.Println(len(ChunkSlice([]int{}, 2)))
+	// Output:
+	// [[a b c] [d e f]]
+	// [[1 2] [3 4] [5]]
+	// [[1 2 3]]
+	// 0
+}
+
+func ExampleClosestString() {
+	flags := []string{"verbose", "version", "output", "help"}
+	fmt.Println(ClosestString("verison", flags))
+	fmt.Println(ClosestString("halp", flags))
+	fmt.Println(ClosestString("output", flags))
+	s, d := ClosestString("anything", nil)
+	fmt.Printf("%q %d\n", s, d)
+
+	// Output:
+	// version 2
+	// help 1
+	// output 0
+	// "" -1
+}
+
+func ExampleCollapseRepeatedLines() {
+	in := "starting\nretrying\nretrying\nretrying\nconnected\nretrying\ndone"
+	if err := CollapseRepeatedLines(strings.NewReader(in), os.Stdout); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println()
+
+	// Output:
+	// starting
+	// retrying
+	// (repeated 3 times)
+	// connected
+	// retrying
+	// done
+}
+
+func ExampleCompactJSONArrays() {
+	b, _ := CompactJSONArrays([]byte(`{"name": "probe", "samples": [1.5e-3, -2, 3E+8, -0.25],
+		"tags": ["a", "b"], "empty": [], "readings": [{"id": 1, "values": [-1e2, 2]}, {"id": 2, "values": []}]}`))
+	fmt.Println(string(b))
+
+	_, err := CompactJSONArrays([]byte(`{"a": [1, 2}`))
+	fmt.Println(err)
+
+	// Output:
+	// {
+	//   "name": "probe",
+	//   "samples": [1.5e-3,-2,3E+8,-0.25],
+	//   "tags": ["a","b"],
+	//   "empty": [],
+	//   "readings": [
+	//     {
+	//       "id": 1,
+	//       "values": [-1e2,2]
+	//     },
+	//     {
+	//       "id": 2,
+	//       "values": []
+	//     }
+	//   ]
+	// }
+	// CompactJSONArrays: invalid JSON
+}
+
 func ExampleConvertCamelToUnderscore() {
 	fmt.Println(ConvertCamelToUnderscore("CamelCase", false))
 	fmt.Println(ConvertCamelToUnderscore("CamelCase", true))
@@ -95,6 +356,92 @@ func ExampleConvertUnderscoreToCamel() {
 	// CamelCase
 }
 
+func ExampleCreateTempDir() {
+	tmpDir, cleanup, _ := CreateTempDir("scratch-")
+	os.WriteFile(filepath.Join(tmpDir, "work.txt"), []byte("data"), 0600)
+	b, _ := DirIsEmpty(tmpDir)
+	fmt.Printf("Temp dir is empty? %+v\n", b)
+
+	cleanup()
+	_, err := os.Stat(tmpDir)
+	fmt.Printf("Temp dir removed? %+v\n", os.IsNotExist(err))
+	// Output:
+	// Temp dir is empty? false
+	// Temp dir removed? true
+}
+
+func ExampleDeinterleaveString() {
+	a, b, _ := DeinterleaveString("abcdef")
+	fmt.Println(a, b)
+	a, b, _ = DeinterleaveString("hwéölrllod")
+	fmt.Println(a, b)
+	_, _, err := DeinterleaveString("abc")
+	fmt.Println(err)
+
+	// Output:
+	// ace bdf
+	// héllo wörld
+	// DeinterleaveString: odd rune count 3
+}
+
+func ExampleDetectContentType() {
+	fmt.Println(DetectContentType([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")))
+	fmt.Println(DetectContentType([]byte("plain text")))
+	fmt.Println(DetectContentType([]byte(`{"a":1}`)))
+	fmt.Println(DetectContentType(nil))
+	// Output:
+	// image/png
+	// text/plain; charset=utf-8
+	// text/plain; charset=utf-8
+	// application/octet-stream
+}
+
+func ExampleDetectIndent() {
+	tabs := "func main() {\n\tif true {\n\t\tfmt.Println()\n\t}\n}\n"
+	twoSpaces := "a:\n  b:\n    c: 1\n    d: 2\n  e: 3\n"
+	fourSpaces := "def f():\n    if x:\n        return 1\n\n    return 2\n"
+	none := "no\nindentation\n"
+	for _, s := range []string{tabs, twoSpaces, fourSpaces, none} {
+		unit, size := DetectIndent([]byte(s))
+		fmt.Printf("%q %d\n", unit, size)
+	}
+
+	// Output:
+	// "\t" 1
+	// " " 2
+	// " " 4
+	// " " 4
+}
+
+func ExampleDiffMaps() {
+	oldConfig := map[string]string{"host": "a", "port": "80", "user": "root"}
+	newConfig := map[string]string{"host": "a", "port": "8080", "tls": "on"}
+	added, removed, changed := DiffMaps(oldConfig, newConfig)
+	fmt.Println(added)
+	fmt.Println(removed)
+	fmt.Println(changed)
+
+	// Output:
+	// map[tls:on]
+	// map[user:root]
+	// map[port:8080]
+}
+
+func ExampleDirExists() {
+	tmpDir, _ := os.MkdirTemp("", "")
+	defer os.RemoveAll(tmpDir)
+	file := filepath.Join(tmpDir, "file")
+	os.WriteFile(file, []byte("data"), 0600)
+
+	fmt.Println(DirExists(filepath.Join(tmpDir, "missing")))
+	fmt.Println(DirExists(tmpDir))
+	fmt.Println(DirExists(file))
+	// Output:
+	// false <nil>
+	// true <nil>
+	// false <nil>
+}
+
 func ExampleDirIsEmpty() {
 	u, _ := user.Current()
 	b, _ := DirIsEmpty(u.HomeDir)
@@ -109,6 +456,54 @@ func ExampleDirIsEmpty() {
 	// Temp dir is empty? true
 }
 
+func ExampleDirIsEmptyIgnoring() {
+	tmpDir, _ := os.MkdirTemp("", "")
+	defer os.RemoveAll(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, ".gitkeep"), nil, 0600)
+
+	b, _ := DirIsEmpty(tmpDir)
+	fmt.Printf("DirIsEmpty:%v\n", b)
+	b, _ = DirIsEmptyIgnoring(tmpDir, []string{".gitkeep", ".DS_Store"})
+	fmt.Printf("Ignoring .gitkeep:%v\n", b)
+	b, _ = DirIsEmptyIgnoring(tmpDir, []string{"*"})
+	fmt.Printf("Ignoring *:%v\n", b)
+
+	os.Symlink(".gitkeep", filepath.Join(tmpDir, "link"))
+	b, _ = DirIsEmptyIgnoring(tmpDir, []string{".gitkeep"})
+	fmt.Printf("With symlink:%v\n", b)
+
+	// Output:
+	// DirIsEmpty:false
+	// Ignoring .gitkeep:true
+	// Ignoring *:false
+	// With symlink:false
+}
+
+func ExampleEditOperations() {
+	s := []rune("kitten")
+	for _, op := range EditOperations("kitten", "sitting") {
+		fmt.Printf("%s %d %q %q\n", op.Type, op.Position, op.Old, op.New)
+		// Apply the operation to show the result is the target.
+		switch op.Type {
+		case EditInsert:
+			s = append(s[:op.Position], append([]rune{op.New}, s[op.Position:]...)...)
+		case EditDelete:
+			s = append(s[:op.Position], s[op.Position+1:]...)
+		case EditSubstitute:
+			s[op.Position] = op.New
+		}
+	}
+	fmt.Println(string(s))
+	fmt.Println(len(EditOperations("flaw", "law")), len(EditOperations("same", "same")))
+
+	// Output:
+	// substitute 0 'k' 's'
+	// substitute 4 'e' 'i'
+	// insert 6 '\x00' 'g'
+	// sitting
+	// 1 0
+}
+
 func ExampleEnumsFromMapIntString() {
 	m := map[int]string{1: "one", 2: "two"}
 	k, v := EnumsFromMapIntString(m)
@@ -117,14 +512,206 @@ func ExampleEnumsFromMapIntString() {
 	// [1 2] [one two]
 }
 
-// Test without the use of a filter
-func ExampleIntSliceIsASCII_true() {
-	someInts := []int{32, 33, 125, 126}
-	filter := map[int]string{}
-	ascii, _ := IntSliceIsASCII(someInts, filter)
-	fmt.Printf("IntSliceIsASCII:%v", ascii)
+func ExampleEnumsFromMapStringInt() {
+	m := map[string]int{"two": 2, "one": 1, "three": 3}
+	k, v := EnumsFromMapStringInt(m)
+	fmt.Printf("%+v %+v", k, v)
 	// Output:
-	// IntSliceIsASCII:true
+	// [one three two] [1 3 2]
+}
+
+func ExampleEscape() {
+	s := "path\\to\tfile\nbell:\a zero-width:\u200b é"
+	e := Escape(s)
+	fmt.Println(e)
+	u, _ := Unescape(e)
+	fmt.Println(u == s)
+
+	// Output:
+	// path\\to\tfile\nbell:\x07 zero-width:\u200b é
+	// true
+}
+
+func ExampleExtractEmails() {
+	text := `Contact bob@example.com or Alice.Smith+tag@mail.example.co.uk; cc bob@example.com.
+Not an email: user@localhost or @example.com`
+	fmt.Println(ExtractEmails(text))
+	fmt.Println(ExtractEmails("nothing here"))
+	// Output:
+	// [bob@example.com Alice.Smith+tag@mail.example.co.uk]
+	// []
+}
+
+func ExampleExtractURLs() {
+	text := `See https://example.com/docs?a=1&b=2, or (http://example.org/path).
+Mirror: ftp://files.example.net/pub. Again: https://example.com/docs?a=1&b=2!`
+	for _, u := range ExtractURLs(text) {
+		fmt.Println(u)
+	}
+	// Output:
+	// https://example.com/docs?a=1&b=2
+	// http://example.org/path
+	// ftp://files.example.net/pub
+}
+
+func ExampleFNV64Checksum() {
+	fmt.Println(FNV64Checksum([]byte("admin")))
+	// The FNV offset basis.
+	fmt.Println(FNV64Checksum([]byte{}))
+	// Output:
+	// 16559146482384667732
+	// 14695981039346656037
+}
+
+func ExampleFNV64ChecksumString() {
+	fmt.Println(FNV64ChecksumString([]byte("admin")))
+	fmt.Println(FNV64ChecksumString(nil))
+	// Output:
+	// e5cde7fdda328454
+	// cbf29ce484222325
+}
+
+func ExampleFileExists() {
+	tmpDir, _ := os.MkdirTemp("", "")
+	defer os.RemoveAll(tmpDir)
+	file := filepath.Join(tmpDir, "file")
+	os.WriteFile(file, []byte("data"), 0600)
+
+	fmt.Println(FileExists(filepath.Join(tmpDir, "missing")))
+	fmt.Println(FileExists(file))
+	fmt.Println(FileExists(tmpDir))
+	// Output:
+	// false <nil>
+	// true <nil>
+	// false <nil>
+}
+
+func ExampleFilterSlice() {
+	evens := FilterSlice([]int{1, 2, 3, 4, 5, 6}, func(i int) bool { return i%2 == 0 })
+	fmt.Println(evens)
+	none := FilterSlice([]int{1, 3}, func(i int) bool { return i%2 == 0 })
+	fmt.Println(none, none != nil)
+	// Output:
+	// [2 4 6]
+	// [] true
+}
+
+func ExampleFindDuplicateJSONKeys() {
+	d, _ := FindDuplicateJSONKeys([]byte(`{"a":1,"b":{"c":1,"c":2,"c":3},"list":[{"x":1},{"x":1,"x":2}],"a":2}`))
+	fmt.Println(d)
+	d, _ = FindDuplicateJSONKeys([]byte(`{"a":1,"b":{"a":1}}`))
+	fmt.Println(d)
+	_, err := FindDuplicateJSONKeys([]byte(`{"a":1`))
+	fmt.Println(err != nil)
+
+	// Output:
+	// [b.c list.1.x a]
+	// []
+	// true
+}
+
+func ExampleFlattenMap() {
+	var m map[string]interface{}
+	json.Unmarshal([]byte(`{"server":{"host":"example.com","port":8080},
+		"users":[{"name":"pat","roles":["admin","dev"]},{"name":"lee"}],"empty":{},"none":[]}`), &m)
+	flat := FlattenMap(m, ".")
+	keys := []string{}
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("%s=%v\n", k, flat[k])
+	}
+
+	// Output:
+	// empty=map[]
+	// none=[]
+	// server.host=example.com
+	// server.port=8080
+	// users.0.name=pat
+	// users.0.roles.0=admin
+	// users.0.roles.1=dev
+	// users.1.name=lee
+}
+
+func ExampleFuzzyContains() {
+	vocabulary := []string{"start", "stop", "status", "restart"}
+	fmt.Println(FuzzyContains("stat", vocabulary, 2))
+	fmt.Println(FuzzyContains("restrat", vocabulary, 2))
+	fmt.Println(FuzzyContains("stop", vocabulary, 0))
+	fmt.Println(FuzzyContains("shutdown", vocabulary, 2))
+	fmt.Println(FuzzyContains("stop", nil, 2))
+
+	// Output:
+	// start true
+	// restart true
+	// stop true
+	//  false
+	//  false
+}
+
+func ExampleGapsBetween() {
+	fmt.Println(GapsBetween([][2]int{{1, 3}, {10, 12}, {2, 5}, {7, 7}}))
+	fmt.Println(GapsBetween([][2]int{{1, 3}, {4, 6}}))
+	// Output:
+	// [[6 6] [8 9]]
+	// []
+}
+
+func ExampleHMACSHA256() {
+	// RFC 4231 test case 2.
+	fmt.Printf("%x\n", HMACSHA256([]byte("Jefe"), []byte("what do ya want for nothing?")))
+	// Output:
+	// 5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843
+}
+
+func ExampleHMACSHA256Base64() {
+	fmt.Println(HMACSHA256Base64([]byte("Jefe"), []byte("what do ya want for nothing?")))
+	// Output:
+	// W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM=
+}
+
+func ExampleHumanizeBytes() {
+	for _, n := range []int64{0, 512, 1536, 1048575, 5 << 30, -3 << 20, math.MaxInt64} {
+		fmt.Println(HumanizeBytes(n))
+	}
+	// Output:
+	// 0 B
+	// 512 B
+	// 1.5 KiB
+	// 1.0 MiB
+	// 5.0 GiB
+	// -3.0 MiB
+	// 8.0 EiB
+}
+
+func ExampleHumanizeDuration() {
+	for _, d := range []time.Duration{
+		0,
+		800 * time.Nanosecond,
+		450 * time.Millisecond,
+		1500 * time.Millisecond,
+		90 * time.Second,
+		2*time.Hour + 3*time.Minute,
+		2*time.Hour + 3*time.Minute + 500*time.Millisecond,
+		26*time.Hour + 5*time.Second,
+		-(49 * time.Hour),
+		math.MinInt64,
+	} {
+		fmt.Println(HumanizeDuration(d))
+	}
+	// Output:
+	// 0s
+	// 800ns
+	// 450ms
+	// 1.5s
+	// 1m30s
+	// 2h3m
+	// 2h3m
+	// 1d2h5s
+	// -2d1h
+	// -106751d23h47m16s
 }
 
 func ExampleInIntSlice() {
@@ -143,6 +730,22 @@ func ExampleInStringSlice() {
 	// true
 }
 
+func ExampleInStringSliceFold() {
+	fmt.Println(InStringSliceFold("HELLO", []string{"hello", "goodbye"}))
+	fmt.Println(InStringSliceFold("Content-Type", []string{"content-type"}))
+	fmt.Println(InStringSliceFold("hello", []string{"nothello", "goodbye"}))
+	fmt.Println(InStringSliceFold("", []string{"a", ""}))
+	fmt.Println(InStringSliceFold("STRASSE", []string{"straße"}))
+	fmt.Println(InStringSliceFold("ΣΑΣ", []string{"σας"}))
+	// Output:
+	// true
+	// true
+	// false
+	// true
+	// false
+	// true
+}
+
 func ExampleInStringSlicePtr() {
 	h := "hello"
 	nh := "nothello"
@@ -154,7 +757,76 @@ func ExampleInStringSlicePtr() {
 	// true
 }
 
+func ExampleIndentJSON() {
+	b, _ := IndentJSON([]byte(`{"b":1,"a":[true,null]}`), "\t")
+	fmt.Println(string(b))
+
+	_, err := IndentJSON([]byte(`{"a":}`), "  ")
+	fmt.Println(err != nil)
+
+	// Output:
+	// {
+	// 	"b": 1,
+	// 	"a": [
+	// 		true,
+	// 		null
+	// 	]
+	// }
+	// true
+}
+
+func ExampleIndexInIntSlice() {
+	fmt.Println(IndexInIntSlice(0, []int{1, 2, 3}))
+	fmt.Println(IndexInIntSlice(1, []int{1, 2, 3}))
+	fmt.Println(IndexInIntSlice(3, []int{1, 3, 2, 3}))
+	// Output:
+	// -1
+	// 0
+	// 1
+}
+
+func ExampleIndexInStringSlice() {
+	fmt.Println(IndexInStringSlice("hello", []string{"nothello", "goodbye"}))
+	fmt.Println(IndexInStringSlice("hello", []string{"hello", "goodbye"}))
+	fmt.Println(IndexInStringSlice("goodbye", []string{"hello", "goodbye", "goodbye"}))
+	fmt.Println(IndexInStringSlice("hello", nil))
+	// Output:
+	// -1
+	// 0
+	// 1
+	// -1
+}
+
 // Test with the use of a filter
+func ExampleIntSliceInRange() {
+	// Printable ASCII plus tab.
+	withTab := []int{'a', '\t', 'b', '~'}
+	inRange, _ := IntSliceInRange(withTab, 9, 126, map[int]string{})
+	fmt.Printf("IntSliceInRange:%v\n", inRange)
+	inRange, _ = IntSliceInRange(withTab, 32, 126, map[int]string{})
+	fmt.Printf("IntSliceInRange:%v\n", inRange)
+	_, err := IntSliceInRange(withTab, 9, 126, map[int]string{'a': "", '\t': "", 'b': "", '~': ""})
+	fmt.Printf("Error:%v\n", err)
+	// All values filtered is never in range, even when the range includes 0.
+	inRange, err = IntSliceInRange([]int{1, 2}, 0, 10, map[int]string{1: "", 2: ""})
+	fmt.Printf("IntSliceInRange:%v, Error:%v", inRange, err)
+	// Output:
+	// IntSliceInRange:true
+	// IntSliceInRange:false
+	// Error:MinMaxIntSlice: all inputs were filtered
+	// IntSliceInRange:false, Error:MinMaxIntSlice: all inputs were filtered
+}
+
+// Test without the use of a filter
+func ExampleIntSliceIsASCII_true() {
+	someInts := []int{32, 33, 125, 126}
+	filter := map[int]string{}
+	ascii, _ := IntSliceIsASCII(someInts, filter)
+	fmt.Printf("IntSliceIsASCII:%v", ascii)
+	// Output:
+	// IntSliceIsASCII:true
+}
+
 func ExampleIntSliceIsASCII_false() {
 	someInts := []int{10, 1, -50, 1000, -10, -1, 50, -1000}
 	filter := map[int]string{}
@@ -164,7 +836,7 @@ func ExampleIntSliceIsASCII_false() {
 	// IntSliceIsASCII:false
 }
 
-// Test the case where all input values are also included in the filter; should panic.
+// Test the case where all input values are also included in the filter; should error.
 func ExampleIntSliceIsASCII_allValuesFiltered() {
 	someInts := []int{32, 33, 126, 127}
 	filter := map[int]string{32: "", 33: "", 126: "", 127: ""}
@@ -183,57 +855,404 @@ func ExampleIntSliceRemoveDuplicates() {
 	// [1 2 3 4 7 8]
 }
 
-func ExampleMD5ChecksumBase64() {
-	fmt.Printf("%s", MD5ChecksumBase64([]byte("admin:Western Digital Corporation:admin")))
+func ExampleIntSliceRemoveDuplicatesStable() {
+	fmt.Println(IntSliceRemoveDuplicatesStable([]int{3, 1, 3, 2, 1}))
+	fmt.Println(IntSliceRemoveDuplicatesStable([]int{1, 2, 3, 4, 4, 1, 7, 8}))
+	fmt.Println(IntSliceRemoveDuplicatesStable(nil))
 	// Output:
-	// l+uthS0Nq/1rca4m//Yfow==
+	// [3 1 2]
+	// [1 2 3 4 7 8]
+	// []
 }
 
-func ExampleMD5Checksum() {
-	fmt.Printf("% 02x", MD5Checksum([]byte("admin:Western Digital Corporation:admin")))
+func ExampleInterleaveStrings() {
+	s, _ := InterleaveStrings("ace", "bdf")
+	fmt.Println(s)
+
+	// Round trip with multibyte runes.
+	s, _ = InterleaveStrings("héllo", "wörld")
+	a, b, _ := DeinterleaveString(s)
+	fmt.Println(s, a, b)
+
+	_, err := InterleaveStrings("ab", "c")
+	fmt.Println(err)
+
 	// Output:
-	// 97 eb ad 85 2d 0d ab fd 6b 71 ae 26 ff f6 1f a3
+	// abcdef
+	// hwéölrllod héllo wörld
+	// InterleaveStrings: rune counts differ, 2 != 1
 }
 
-// Test with the use of a filter
-func ExampleMinMaxIntSlice() {
-	someInts := []int{10, 1, -50, 1000, -10, -1, 50, -1000}
-	filter := map[int]string{-1000: "don't like this value", 1000: "or this"}
-	min, max, _ := MinMaxIntSlice(someInts, filter)
-	fmt.Printf("min:%v, max:%v", min, max)
+func ExampleInvertMap() {
+	m := InvertMap(map[string]int{"one": 1, "two": 2, "three": 3})
+	k, v := EnumsFromMapIntString(m)
+	fmt.Printf("%+v %+v\n", k, v)
+
+	// With values that are not unique, one of the keys is kept.
+	c := InvertMap(map[string]int{"one": 1, "uno": 1, "two": 2})
+	fmt.Println(len(c), c[1] == "one" || c[1] == "uno", c[2])
 	// Output:
-	// min:-50, max:50
+	// [1 2 3] [one two three]
+	// 2 true two
 }
 
-// Test without the use of a filter.
-func ExampleMinMaxIntSlice_noFilter() {
-	someInts := []int{10, 1, -50, 1000, -10, -1, 50, -1000}
-	filter := map[int]string{}
-	min, max, _ := MinMaxIntSlice(someInts, filter)
-	fmt.Printf("min:%v, max:%v", min, max)
+func ExampleInvertMapToSlice() {
+	m := InvertMapToSlice(map[string]int{"one": 1, "uno": 1, "eins": 1, "two": 2})
+	for _, v := range []int{1, 2} {
+		sort.Strings(m[v])
+		fmt.Println(v, m[v])
+	}
+	fmt.Println(len(m))
 	// Output:
-	// min:-1000, max:1000
+	// 1 [eins one uno]
+	// 2 [two]
+	// 2
 }
 
-// Test with nil filter
-func ExampleMinMaxIntSlice_nilFilter() {
-	someInts := []int{10, 1, -50, 1000, -10, -1, 50, -1000}
-	min, max, _ := MinMaxIntSlice(someInts, nil)
-	fmt.Printf("min:%v, max:%v", min, max)
+func ExampleIsContiguous() {
+	fmt.Println(IsContiguous([]int{3, 1, 2, 2, 4}))
+	fmt.Println(IsContiguous([]int{5, 1, 2}))
+	fmt.Println(IsContiguous([]int{}))
+	// Wide, sparse input is checked without visiting the values between min and max.
+	fmt.Println(IsContiguous([]int{0, 1 << 33}))
+	fmt.Println(IsContiguous([]int{math.MinInt, math.MaxInt}))
+	fmt.Println(IsContiguous([]int{math.MaxInt, math.MaxInt - 1}))
 	// Output:
-	// min:-1000, max:1000
+	// true
+	// false
+	// true
+	// false
+	// false
+	// true
 }
 
-// Test the case where all input values are also included in the filter; should panic.
-func ExampleMinMaxIntSlice_allValuesFiltered() {
-	someInts := []int{10, 1, -50, 1000, -10, -1, 50, -1000}
-	filter := map[int]string{10: "", 1: "", -50: "", 1000: "", -10: "", -1: "", 50: "", -1000: ""}
-	_, _, err := MinMaxIntSlice(someInts, filter)
-	fmt.Printf("Error:%v", err)
+func ExampleJSONChecksumIgnoring() {
+	ignore := []string{"timestamp", "requestId"}
+	a, _ := JSONChecksumIgnoring([]byte(`{"event":"login","user":"pat","timestamp":"2023-01-01T00:00:00Z",
+		"meta":{"requestId":"r1","ip":"10.0.0.1"}}`), ignore)
+	b, _ := JSONChecksumIgnoring([]byte(`{"user":"pat","event":"login","timestamp":"2023-01-02T09:30:00Z",
+		"meta":{"ip":"10.0.0.1","requestId":"r2"}}`), ignore)
+	c, _ := JSONChecksumIgnoring([]byte(`{"user":"pat","event":"logout","timestamp":"2023-01-02T09:30:00Z",
+		"meta":{"ip":"10.0.0.1","requestId":"r2"}}`), ignore)
+	fmt.Println(len(a), a == b, a == c)
+
+	_, err := JSONChecksumIgnoring([]byte(`{"a":`), ignore)
+	fmt.Println(err != nil)
+
+	// Output:
+	// 40 true false
+	// true
+}
+
+func ExampleJSONChecksumOf() {
+	watch := []string{"replicas", "image"}
+	a, _ := JSONChecksumOf([]byte(`{"name":"web","updated":"2023-01-01","spec":{"replicas":3,
+		"containers":[{"image":"nginx:1.25","restarts":0}]}}`), watch)
+	// Only unlisted fields change.
+	b, _ := JSONChecksumOf([]byte(`{"name":"web-renamed","updated":"2023-02-01","spec":{"replicas":3,
+		"containers":[{"restarts":5,"image":"nginx:1.25"}]}}`), watch)
+	// A listed field changes.
+	c, _ := JSONChecksumOf([]byte(`{"name":"web","updated":"2023-01-01","spec":{"replicas":4,
+		"containers":[{"image":"nginx:1.25","restarts":0}]}}`), watch)
+	fmt.Println(len(a), a == b, a == c)
+
+	// Output:
+	// 40 true false
+}
+
+func ExampleJSONStructureMatch() {
+	actual := []byte(`{"id":17,"name":"widget","tags":["a","b","c"],"owner":{"id":3,"email":null}}`)
+	mock := []byte(`{"owner":{"email":null,"id":1},"tags":["x"],"name":"mock","id":0}`)
+	fmt.Println(JSONStructureMatch(actual, mock))
+
+	changed := []byte(`{"id":"17","name":"widget","tags":["a",2],"owner":{"id":3},"extra":true}`)
+	fmt.Println(JSONStructureMatch(actual, changed))
+
+	_, _, err := JSONStructureMatch(actual, []byte(`{`))
+	fmt.Println(err != nil)
+
+	// Output:
+	// true [] <nil>
+	// false [extra id owner.email tags.1] <nil>
+	// true
+}
+
+func ExampleLevenshteinDistance() {
+	fmt.Println(LevenshteinDistance("kitten", "sitting"))
+	fmt.Println(LevenshteinDistance("same", "same"))
+	fmt.Println(LevenshteinDistance("", "abc"))
+	// Runes, not bytes; each accented character is one substitution.
+	fmt.Println(LevenshteinDistance("héllo", "hello"))
+	fmt.Println(LevenshteinDistance("日本語", "日本"))
+
+	// Output:
+	// 3
+	// 0
+	// 3
+	// 1
+	// 1
+}
+
+func ExampleMD5ChecksumBase64() {
+	fmt.Printf("%s", MD5ChecksumBase64([]byte("admin:Western Digital Corporation:admin")))
+	// Output:
+	// l+uthS0Nq/1rca4m//Yfow==
+}
+
+func ExampleMD5ChecksumBase64URL() {
+	fmt.Println(MD5ChecksumBase64URL([]byte("admin:Western Digital Corporation:admin")))
+	fmt.Println(MD5ChecksumBase64URL([]byte("admin")) == MD5ChecksumBase64([]byte("admin")))
+	// Output:
+	// l-uthS0Nq_1rca4m__Yfow==
+	// true
+}
+
+func ExampleMD5Checksum() {
+	fmt.Printf("% 02x", MD5Checksum([]byte("admin:Western Digital Corporation:admin")))
+	// Output:
+	// 97 eb ad 85 2d 0d ab fd 6b 71 ae 26 ff f6 1f a3
+}
+
+func ExampleMapSlice() {
+	s := MapSlice([]int{1, 22, 333}, func(i int) string { return strconv.Itoa(i) })
+	fmt.Printf("%q\n", s)
+	fmt.Println(MapSlice([]string{"a", "bb"}, func(s string) int { return len(s) }))
+	// Output:
+	// ["1" "22" "333"]
+	// [1 2]
+}
+
+func ExampleMergeIntervals() {
+	// Overlapping, touching, and adjacent intervals merge.
+	fmt.Println(MergeIntervals([][2]int{{5, 8}, {1, 3}, {3, 4}, {9, 10}}))
+	// Disjoint intervals are only sorted; reversed intervals are normalized.
+	fmt.Println(MergeIntervals([][2]int{{20, 25}, {12, 10}, {1, 2}}))
+	fmt.Println(MergeIntervals(nil))
+	// Output:
+	// [[1 10]]
+	// [[1 2] [10 12] [20 25]]
+	// []
+}
+
+// Test with the use of a filter
+func ExampleMinMaxIntSlice() {
+	someInts := []int{10, 1, -50, 1000, -10, -1, 50, -1000}
+	filter := map[int]string{-1000: "don't like this value", 1000: "or this"}
+	min, max, _ := MinMaxIntSlice(someInts, filter)
+	fmt.Printf("min:%v, max:%v", min, max)
+	// Output:
+	// min:-50, max:50
+}
+
+// Test without the use of a filter.
+func ExampleMinMaxIntSlice_noFilter() {
+	someInts := []int{10, 1, -50, 1000, -10, -1, 50, -1000}
+	filter := map[int]string{}
+	min, max, _ := MinMaxIntSlice(someInts, filter)
+	fmt.Printf("min:%v, max:%v", min, max)
+	// Output:
+	// min:-1000, max:1000
+}
+
+// Test with nil filter
+func ExampleMinMaxIntSlice_nilFilter() {
+	someInts := []int{10, 1, -50, 1000, -10, -1, 50, -1000}
+	min, max, _ := MinMaxIntSlice(someInts, nil)
+	fmt.Printf("min:%v, max:%v", min, max)
+	// Output:
+	// min:-1000, max:1000
+}
+
+// Test the case where all input values are also included in the filter; should panic.
+func ExampleMinMaxIntSlice_allValuesFiltered() {
+	someInts := []int{10, 1, -50, 1000, -10, -1, 50, -1000}
+	filter := map[int]string{10: "", 1: "", -50: "", 1000: "", -10: "", -1: "", 50: "", -1000: ""}
+	_, _, err := MinMaxIntSlice(someInts, filter)
+	fmt.Printf("Error:%v", err)
 	// Output:
 	// Error:MinMaxIntSlice: all inputs were filtered
 }
 
+func ExampleMinifyJSON() {
+	b, _ := MinifyJSON([]byte("{\n  \"b\": 1,\n  \"a\": [ \"x y\", 2 ]\n}"))
+	fmt.Println(string(b))
+
+	_, err := MinifyJSON([]byte(`{"a":1} {"b":2}`))
+	fmt.Println(err != nil)
+
+	// Output:
+	// {"b":1,"a":["x y",2]}
+	// true
+}
+
+func ExampleMissingFromRange() {
+	fmt.Println(MissingFromRange([]int{3, 1, 2, 2, 4}))
+	fmt.Println(MissingFromRange([]int{9, 1, 2, 6, 2}))
+	fmt.Println(MissingFromRange([]int{-2, 1}))
+	// Output:
+	// []
+	// [3 4 5 7 8]
+	// [-1 0]
+}
+
+func ExampleNaturalLess() {
+	fmt.Println(NaturalLess("file2", "file10"), NaturalLess("file10", "file2"))
+
+	files := []string{"file10.txt", "file2.txt", "file1.txt", "file02.txt", "file", "v1.10.0", "v1.9.3", "v1.9.10"}
+	sort.Slice(files, func(i, j int) bool { return NaturalLess(files[i], files[j]) })
+	fmt.Println(files)
+
+	// Output:
+	// true false
+	// [file file1.txt file2.txt file02.txt file10.txt v1.9.3 v1.9.10 v1.10.0]
+}
+
+func ExampleNormalizeUnicode() {
+	// Both are "café"; the first with a single rune, the second with a combining accent.
+	composed := "café"
+	decomposed := "café"
+	fmt.Println(composed == decomposed)
+
+	c, _ := NormalizeUnicode(composed, "NFC")
+	d, _ := NormalizeUnicode(decomposed, "NFC")
+	fmt.Println(c == d, len(c), len(d))
+
+	c, _ = NormalizeUnicode(composed, "nfd")
+	fmt.Println(c == decomposed)
+
+	k, _ := NormalizeUnicode("ﬁ", "NFKC")
+	fmt.Println(k)
+
+	_, err := NormalizeUnicode(composed, "NFX")
+	fmt.Println(err)
+
+	// Output:
+	// false
+	// true 5 5
+	// true
+	// fi
+	// NormalizeUnicode: unknown form "NFX"
+}
+
+func ExamplePadLeft() {
+	for _, s := range []string{"7", "42", "héllo", "日本"} {
+		fmt.Printf("[%s]\n", PadLeft(s, 5, ' '))
+	}
+	fmt.Println(PadLeft("ff", 4, '0'))
+	fmt.Println(PadLeft("too long", 3, '*'), PadLeft("abc", 0, '*'), PadLeft("abc", -1, '*'))
+
+	// Output:
+	// [    7]
+	// [   42]
+	// [héllo]
+	// [   日本]
+	// 00ff
+	// too long abc abc
+}
+
+func ExamplePadRight() {
+	for _, s := range []string{"7", "42", "héllo", "日本"} {
+		fmt.Printf("[%s]\n", PadRight(s, 5, '.'))
+	}
+	fmt.Println(PadRight("too long", 3, '*'), PadRight("abc", 0, '*'))
+
+	// Output:
+	// [7....]
+	// [42...]
+	// [héllo]
+	// [日本...]
+	// too long abc
+}
+
+func ExampleParseBytes() {
+	for _, s := range []string{"512", "1.5 KiB", " 2kb ", "1.5GB", "-3 MiB", "10 XB", "KB"} {
+		n, err := ParseBytes(s)
+		fmt.Println(n, err)
+	}
+	// Output:
+	// 512 <nil>
+	// 1536 <nil>
+	// 2000 <nil>
+	// 1500000000 <nil>
+	// -3145728 <nil>
+	// 0 ParseBytes: invalid unit in "10 XB"
+	// 0 ParseBytes: invalid number in "KB"
+}
+
+func ExampleParseDataURI() {
+	m, d, _ := ParseDataURI("data:image/png;base64,iVBORw0KGgo=")
+	fmt.Printf("%s % 02x\n", m, d)
+
+	m, d, _ = ParseDataURI("data:,Hello%2C%20World%21")
+	fmt.Printf("%s %s\n", m, d)
+
+	m, d, _ = ParseDataURI("data:;charset=utf-8;base64,aMOpbGxv")
+	fmt.Printf("%s %s\n", m, d)
+
+	_, _, err := ParseDataURI("data:text/plain;base64,%%%")
+	fmt.Println(err)
+
+	// Output:
+	// image/png 89 50 4e 47 0d 0a 1a 0a
+	// text/plain Hello, World!
+	// text/plain;charset=utf-8 héllo
+	// ParseDataURI: illegal base64 data at input byte 0
+}
+
+func ExampleParseINI() {
+	ini := `; global settings
+debug = true
+
+[server]
+host = example.com
+port=8080
+# not used
+tls
+
+[client]
+retries = 3
+`
+	m, _ := ParseINI(strings.NewReader(ini))
+	for _, section := range []string{"", "server", "client"} {
+		fmt.Printf("%q %v\n", section, m[section])
+	}
+
+	_, err := ParseINI(strings.NewReader("a=1\n[broken\n"))
+	fmt.Println(err)
+
+	// Output:
+	// "" map[debug:true]
+	// "server" map[host:example.com port:8080 tls:]
+	// "client" map[retries:3]
+	// ParseINI: malformed section header on line 2
+}
+
+func ExamplePercentChange() {
+	pc, _ := PercentChange(3, 4)
+	fmt.Println(Round(pc, 2))
+	pc, _ = PercentChange(200, 150)
+	fmt.Println(Round(pc, 2))
+	pc, _ = PercentChange(-10, -5)
+	fmt.Println(Round(pc, 2))
+	_, err := PercentChange(0, 5)
+	fmt.Println(err)
+
+	// Output:
+	// 33.33
+	// -25
+	// 50
+	// PercentChange: old value is 0
+}
+
+func ExamplePercentChangeInf() {
+	fmt.Println(Round(PercentChangeInf(3, 4), 2))
+	fmt.Println(PercentChangeInf(0, 5), PercentChangeInf(0, -5), PercentChangeInf(0, 0))
+
+	// Output:
+	// 33.33
+	// +Inf -Inf 0
+}
+
 func ExamplePrettyJSON() {
 	testJSON := []byte(
 		`"field": [ 
@@ -264,6 +1283,105 @@ func ExamplePrettyJSON() {
 	// "field": [1.1,2,3],
 }
 
+func ExampleRecase() {
+	for _, s := range []Style{StyleCamel, StyleLowerCamel, StyleSnake, StyleScreamingSnake, StyleKebab, StyleTitle} {
+		fmt.Println(Recase("parse_http_json_response", s))
+	}
+	fmt.Println(Recase("HTTPServerID", StyleSnake))
+	fmt.Println(Recase("user-name v2", StyleCamel))
+
+	// Output:
+	// ParseHTTPJSONResponse
+	// parseHTTPJSONResponse
+	// parse_http_json_response
+	// PARSE_HTTP_JSON_RESPONSE
+	// parse-http-json-response
+	// Parse HTTP JSON Response
+	// http_server_id
+	// UserNameV2
+}
+
+func ExampleRedactJSONKeys() {
+	body := []byte(`{"user":"pat","password":"hunter2","auth":{"token":"abc","password":{"old":"x"}},
+		"sessions":[{"id":1,"token":"t1"},{"id":2,"Token":"t2"}]}`)
+	b, _ := RedactJSONKeys(body, []string{"password", "token"})
+	fmt.Println(string(b))
+
+	b, _ = RedactJSONKeysFold(body, []string{"password", "token"})
+	fmt.Println(string(b))
+
+	b, _ = RedactJSONKeys([]byte(`{"query":"a < b && c > d","token":"t"}`), []string{"token"})
+	fmt.Println(string(b))
+
+	_, err := RedactJSONKeys([]byte(`{"password":`), []string{"password"})
+	fmt.Println(err != nil)
+
+	// Output:
+	// {"auth":{"password":"[REDACTED]","token":"[REDACTED]"},"password":"[REDACTED]","sessions":[{"id":1,"token":"[REDACTED]"},{"Token":"t2","id":2}],"user":"pat"}
+	// {"auth":{"password":"[REDACTED]","token":"[REDACTED]"},"password":"[REDACTED]","sessions":[{"id":1,"token":"[REDACTED]"},{"Token":"[REDACTED]","id":2}],"user":"pat"}
+	// {"query":"a < b && c > d","token":"[REDACTED]"}
+	// true
+}
+
+func ExampleReduceSlice() {
+	fmt.Println(ReduceSlice([]int{1, 2, 3, 4}, 0, func(sum, i int) int { return sum + i }))
+	fmt.Println(ReduceSlice([]string{"a", "bb", "ccc"}, 0, func(n int, s string) int { return n + len(s) }))
+	fmt.Println(ReduceSlice([]int{}, 10, func(sum, i int) int { return sum + i }))
+	// Output:
+	// 10
+	// 6
+	// 10
+}
+
+func ExampleRemoveDuplicates() {
+	fmt.Println(RemoveDuplicates([]string{"b", "a", "b", "c", "a"}))
+
+	type key struct {
+		name string
+		id   int
+	}
+	fmt.Println(RemoveDuplicates([]key{{"a", 1}, {"b", 2}, {"a", 1}, {"a", 2}}))
+
+	var nilSlice []string
+	r := RemoveDuplicates(nilSlice)
+	fmt.Println(r, r != nil)
+
+	// Output:
+	// [b a c]
+	// [{a 1} {b 2} {a 2}]
+	// [] true
+}
+
+func ExampleRenameWithMapping() {
+	columns := []string{"id", "name", "name", "", "id"}
+	names, mapping := RenameWithMapping(columns, "%s_%d")
+	fmt.Println(names)
+	for i := range columns {
+		fmt.Printf("%d %q -> %q\n", i, columns[i], mapping[i])
+	}
+
+	// Output:
+	// [id name name_2 _ id_2]
+	// 0 "id" -> "id"
+	// 1 "name" -> "name"
+	// 2 "name" -> "name_2"
+	// 3 "" -> "_"
+	// 4 "id" -> "id_2"
+}
+
+func ExampleReverseString() {
+	fmt.Println(ReverseString("hello"))
+	fmt.Println(ReverseString("héllo"))
+	fmt.Println(ReverseString("go🙂!"))
+	fmt.Printf("%q\n", ReverseString(""))
+
+	// Output:
+	// olleh
+	// olléh
+	// !🙂og
+	// ""
+}
+
 func ExampleRound_pi0() {
 	rounded := Round(math.Pi, 0)
 	fmt.Printf("%.0f", rounded)
@@ -299,58 +1417,1955 @@ func ExampleRound_n2h() {
 	// 1.50
 }
 
+func ExampleRound_max() {
+	fmt.Println(Round(math.MaxFloat64, 0) == math.MaxFloat64)
+	fmt.Println(Round(math.MaxFloat64, 2) == math.MaxFloat64)
+	fmt.Println(Round(1e308, 2) == 1e308)
+	fmt.Println(Round(-math.MaxFloat64, 400) == -math.MaxFloat64)
+	// Output:
+	// true
+	// true
+	// true
+	// true
+}
+
+func ExampleRound_negativeDigits() {
+	fmt.Println(Round(12345, -2))
+	fmt.Println(Round(12355, -1))
+	fmt.Println(Round(-12345, -3))
+	fmt.Println(Round(12345, -400))
+	// Output:
+	// 12300
+	// 12360
+	// -12000
+	// 0
+}
+
+func ExampleRoundDown() {
+	fmt.Printf("%.2f\n", RoundDown(1.499, 2))
+	fmt.Printf("%.2f\n", RoundDown(1.495, 2))
+	fmt.Printf("%.2f\n", RoundDown(-1.495, 2))
+	fmt.Printf("%.2f\n", RoundDown(1.1, 2))
+	fmt.Printf("%.0f\n", RoundDown(math.Pi, 0))
+	// Output:
+	// 1.49
+	// 1.49
+	// -1.50
+	// 1.10
+	// 3
+}
+
+func ExampleRoundHalfEven_pi5() {
+	rounded := RoundHalfEven(math.Pi, 5)
+	fmt.Printf("%.5f", rounded)
+	// Output:
+	// 3.14159
+}
+
+func ExampleRoundHalfEven_n2l() {
+	rounded := RoundHalfEven(1.485, 2)
+	fmt.Printf("%.2f", rounded)
+	// Output:
+	// 1.48
+}
+
+func ExampleRoundHalfEven_n2h() {
+	rounded := RoundHalfEven(1.495, 2)
+	fmt.Printf("%.2f", rounded)
+	// Output:
+	// 1.50
+}
+
+func ExampleRoundHalfEven_n0() {
+	fmt.Println(RoundHalfEven(0.5, 0), RoundHalfEven(1.5, 0), RoundHalfEven(2.5, 0), RoundHalfEven(-2.5, 0))
+	// Output:
+	// 0 2 2 -2
+}
+
+func ExampleRoundUp() {
+	fmt.Printf("%.2f\n", RoundUp(1.491, 2))
+	fmt.Printf("%.2f\n", RoundUp(1.495, 2))
+	fmt.Printf("%.2f\n", RoundUp(-1.495, 2))
+	fmt.Printf("%.2f\n", RoundUp(1.1, 2))
+	fmt.Printf("%.0f\n", RoundUp(math.Pi, 0))
+	// Output:
+	// 1.50
+	// 1.50
+	// -1.49
+	// 1.10
+	// 4
+}
+
 func ExampleSHA1ChecksumBase64() {
 	fmt.Printf("%s", SHA1ChecksumBase64([]byte("admin")))
 	// Output:
 	// 0DPiKuNIrrVmD8IUCuw1hQxNqZc=
 }
 
+func ExampleSHA1ChecksumBase64URL() {
+	fmt.Println(SHA1ChecksumBase64([]byte("abc")))
+	fmt.Println(SHA1ChecksumBase64URL([]byte("abc")))
+	// Output:
+	// qZk+NkcGgWq6PiVxeFDCbJzQ2J0=
+	// qZk-NkcGgWq6PiVxeFDCbJzQ2J0=
+}
+
 func ExampleSHA1Checksum() {
 	fmt.Printf("% 02x", SHA1Checksum([]byte("admin")))
 	// Output:
 	// d0 33 e2 2a e3 48 ae b5 66 0f c2 14 0a ec 35 85 0c 4d a9 97
 }
 
-func ExampleUniqueStrings() {
-	s := []string{"paul", "paul", "bruce", "jeff", "bruce", "bruce", "bob", "paul", "", ""}
-	o, b := UniqueStrings(s, "%s_%03d")
-	fmt.Println(strings.Join(o, "|"), b)
+func ExampleSecureCompare() {
+	token := "s3cr3t-t0ken"
+	fmt.Println(SecureCompare(token, "s3cr3t-t0ken"))
+	fmt.Println(SecureCompare(token, "s3cr3t-t0kem"))
+	fmt.Println(SecureCompare(token, "s3cr3t"))
+	fmt.Println(SecureCompare("", ""))
+	// Output:
+	// true
+	// false
+	// false
+	// true
+}
 
-	s = []string{"paul", "bruce", "jeff"}
-	o, b = UniqueStrings(s, "%s_%03d")
-	fmt.Println(strings.Join(o, "|"), b)
+func ExampleSideBySideDiff() {
+	a := "host=example.com\nport=8080\nuser=admin\ndebug=false\n"
+	b := "host=example.com\nport=9090\ndebug=false\nlog=/var/log/service/output.log\n"
+	fmt.Print(SideBySideDiff(a, b, 16))
+	// Output:
+	// host=example.com   host=example.com
+	// port=8080        | port=9090
+	// user=admin       <
+	// debug=false        debug=false
+	//                  > log=/var/log/ser
+	//                    vice/output.log
+}
 
+func ExampleSortMapByValueDesc() {
+	m := map[string]int{"pear": 2, "apple": 5, "fig": 2, "banana": 7, "kiwi": 2}
+	k, v := SortMapByValueDesc(m)
+	fmt.Printf("%+v %+v\n", k, v)
+	k, v = SortMapByValueDesc(map[string]int{})
+	fmt.Printf("%+v %+v\n", k, v)
 	// Output:
-	// paul|paul_002|bruce|jeff|bruce_002|bruce_003|bob|paul_003|_|__002 true
-	// paul|bruce|jeff false
+	// [banana apple fig kiwi pear] [7 5 2 2 2]
+	// [] []
 }
 
-func ExampleVerifyMapKeysStringString() {
-	kf := []string{"1", "4"}
-	kt := []string{"1", "2"}
-	m := map[string]string{"1": "one", "2": "two"}
-	fmt.Printf("Missing key:%v\n", VerifyMapKeysStringString(kf, m))
-	fmt.Printf("Contains keys:%v", VerifyMapKeysStringString(kt, m))
+func ExampleSplitWords() {
+	fmt.Printf("%q\n", SplitWords("HTTPServerID"))
+	fmt.Printf("%q\n", SplitWords("lowerCamelCase"))
+	fmt.Printf("%q\n", SplitWords("snake_case__value"))
+	fmt.Printf("%q\n", SplitWords("kebab-case.dotted words"))
+	fmt.Printf("%q\n", SplitWords("version2Name"))
+	fmt.Printf("%q\n", SplitWords(""))
 
 	// Output:
-	// Missing key:false
-	// Contains keys:true
+	// ["HTTP" "Server" "ID"]
+	// ["lower" "Camel" "Case"]
+	// ["snake" "case" "value"]
+	// ["kebab" "case" "dotted" "words"]
+	// ["version2" "Name"]
+	// []
 }
 
-func TestRequestUsername(t *testing.T) {
-	ts := httptest.NewServer(http.HandlerFunc(testHandlerFuncUser))
-	defer ts.Close()
+func ExampleStringSliceRemoveEmpty() {
+	s := []string{"a", "", " b ", "  ", "\t", "c"}
+	fmt.Printf("%q\n", StringSliceRemoveEmpty(s, false))
+	fmt.Printf("%q\n", StringSliceRemoveEmpty(s, true))
+	fmt.Printf("%q\n", StringSliceRemoveEmpty([]string{"", " "}, true))
+	fmt.Printf("%q\n", s)
 
-	handler := http.HandlerFunc(testHandlerFuncUser)
-	resp := httptest.NewRecorder()
-	req, _ := http.NewRequest(http.MethodGet, "http://TestRequestUsername", nil)
-	req.SetBasicAuth("testUser", "password")
-	handler.ServeHTTP(resp, req)
+	// Output:
+	// ["a" " b " "  " "\t" "c"]
+	// ["a" " b " "c"]
+	// []
+	// ["a" "" " b " "  " "\t" "c"]
+}
 
-	if reqUser != "testUser" {
-		t.Errorf("User was not correct, reqUser:%+v", reqUser)
-	} else {
-		//fmt.Printf("User was:%+v", reqUser)
+func ExampleStringToByteSlice() {
+	b, _ := StringToByteSlice("00 01 02\n03\n")
+	fmt.Println(b)
+	b, _ = StringToByteSlice(ByteSliceToStringFormatted([]byte{0x0a, 0xff}, 1, true, true))
+	fmt.Println(b)
+	_, err := StringToByteSlice("00 0g")
+	fmt.Println(err)
+	_, err = StringToByteSlice("00 123")
+	fmt.Println(err)
+
+	// Output:
+	// [0 1 2 3]
+	// [10 255]
+	// StringToByteSlice: malformed hex byte "0g"
+	// StringToByteSlice: malformed hex byte "123"
+}
+
+func ExampleStructsToCSV() {
+	type record struct {
+		Name     string  `csv:"name"`
+		Count    int     `json:"count,omitempty"`
+		Price    float64 // No tag, the field name is used.
+		Note     *string `csv:"note"`
+		Internal string  `csv:"-"`
+		hidden   bool
+	}
+	note := "has, comma"
+	b, _ := StructsToCSV([]record{{"apple", 3, 1.25, &note, "x", true}, {"pear", 0, 2, nil, "y", false}})
+	fmt.Print(string(b))
+
+	type nested struct {
+		Name  string
+		Inner record
+	}
+	_, err := StructsToCSV([]nested{{Name: "a"}})
+	fmt.Println(err)
+
+	// Output:
+	// name,count,Price,note
+	// apple,3,1.25,"has, comma"
+	// pear,0,2,
+	// StructsToCSV: unsupported type goutil.record for field Inner
+}
+
+func ExampleTruncate() {
+	fmt.Println(Truncate("short", 10, "..."))
+	fmt.Println(Truncate("a long log line", 10, "..."))
+	fmt.Println(Truncate("héllo wörld", 8, "…"))
+	fmt.Println(Truncate("日本語のテキスト", 4, ""))
+	fmt.Printf("%q\n", Truncate("abcdef", 2, "..."))
+
+	// Output:
+	// short
+	// a long ...
+	// héllo w…
+	// 日本語の
+	// ".."
+}
+
+func ExampleUUIDv5() {
+	fmt.Println(UUIDv5(UUIDNamespaceDNS, "python.org"))
+	fmt.Println(UUIDv5(UUIDNamespaceURL, "https://example.com/"))
+	fmt.Println(UUIDv5(UUIDNamespaceDNS, "python.org") == UUIDv5(UUIDNamespaceDNS, "python.org"))
+	// Output:
+	// 886313e1-3b8a-5372-9b90-0c9aee199e5d
+	// dd2c1780-811a-5296-81c5-178a0ef488bc
+	// true
+}
+
+func ExampleUnescape() {
+	u, _ := Unescape(`line1\nline2\ttabbed \"quoted\" \x41é日 back\\slash`)
+	fmt.Println(u)
+	fmt.Println(Escape(u))
+
+	_, err := Unescape(`bad \q escape`)
+	fmt.Println(err)
+	_, err = Unescape(`short \u12`)
+	fmt.Println(err)
+
+	// Output:
+	// line1
+	// line2	tabbed "quoted" Aé日 back\slash
+	// line1\nline2\ttabbed "quoted" Aé日 back\\slash
+	// Unescape: invalid escape sequence "\\q" at byte 4
+	// Unescape: incomplete escape sequence "\\u12" at byte 6
+}
+
+func ExampleUnflattenMap() {
+	m, _ := UnflattenMap(map[string]interface{}{"server.host": "example.com", "server.port": 8080,
+		"users.0.name": "pat", "users.1.name": "lee", "empty": map[string]interface{}{}}, ".")
+	b, _ := json.Marshal(m)
+	fmt.Println(string(b))
+
+	_, err := UnflattenMap(map[string]interface{}{"a": 1, "a.b": 2}, ".")
+	fmt.Println(err)
+
+	// Output:
+	// {"empty":{},"server":{"host":"example.com","port":8080},"users":[{"name":"pat"},{"name":"lee"}]}
+	// UnflattenMap: key "a.b" conflicts with key "a"
+}
+
+func ExampleUniqueStrings() {
+	s := []string{"paul", "paul", "bruce", "jeff", "bruce", "bruce", "bob", "paul", "", ""}
+	o, b := UniqueStrings(s, "%s_%03d")
+	fmt.Println(strings.Join(o, "|"), b)
+
+	s = []string{"paul", "bruce", "jeff"}
+	o, b = UniqueStrings(s, "%s_%03d")
+	fmt.Println(strings.Join(o, "|"), b)
+
+	// Output:
+	// paul|paul_002|bruce|jeff|bruce_002|bruce_003|bob|paul_003|_|__002 true
+	// paul|bruce|jeff false
+}
+
+func ExampleUniqueStringsExcluding() {
+	existing := map[string]struct{}{"paul": {}, "bruce": {}, "bruce_002": {}}
+	s := []string{"paul", "bruce", "jeff", "jeff", ""}
+	o, b := UniqueStringsExcluding(s, existing, "%s_%03d")
+	fmt.Println(strings.Join(o, "|"), b)
+
+	// Output:
+	// paul_002|bruce_003|jeff|jeff_002|_ true
+}
+
+func ExampleUnitConverter_Convert() {
+	bytesConverter := NewUnitConverter(map[string]float64{"B": 1, "KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30}, 2)
+	b, _ := bytesConverter.Convert(1.5, "MiB", "B")
+	fmt.Printf("%.0f\n", b)
+	fmt.Println(bytesConverter.Convert(2560, "MiB", "GiB"))
+
+	timeConverter := NewUnitConverter(map[string]float64{"ms": 0.001, "s": 1, "min": 60, "h": 3600}, 3)
+	fmt.Println(timeConverter.Convert(90, "min", "h"))
+	fmt.Println(timeConverter.Convert(1234, "ms", "s"))
+
+	_, err := bytesConverter.Convert(1, "MB", "B")
+	fmt.Println(err)
+
+	// Output:
+	// 1572864
+	// 2.5 <nil>
+	// 1.5 <nil>
+	// 1.234 <nil>
+	// UnitConverter: unknown unit "MB"
+}
+
+func ExampleValidateKeys() {
+	required := []string{"name", "email"}
+	optional := []string{"phone"}
+	fmt.Println(ValidateKeys(map[string]interface{}{"name": "pat", "email": "pat@example.com"}, required, optional))
+	fmt.Println(ValidateKeys(map[string]interface{}{"name": "pat", "phone": "555"}, required, optional))
+	fmt.Println(ValidateKeys(map[string]interface{}{"name": "pat", "email": "e", "admin": true, "Name": "x"}, required, optional))
+	fmt.Println(ValidateKeys(map[string]interface{}{"admin": true}, required, optional))
+
+	// Output:
+	// <nil>
+	// ValidateKeys: missing required keys ["email"]
+	// ValidateKeys: unexpected keys ["Name" "admin"]
+	// ValidateKeys: missing required keys ["name" "email"]; unexpected keys ["admin"]
+}
+
+func ExampleVerifyMapKeys() {
+	m := map[string]interface{}{"name": "x", "count": 1.0}
+	fmt.Println(VerifyMapKeys([]string{"name", "count"}, m))
+	fmt.Println(VerifyMapKeys([]string{"name", "size"}, m))
+
+	type foo struct{}
+	fmt.Println(VerifyMapKeys([]int{1, 2}, map[int]foo{1: {}, 2: {}, 3: {}}))
+	// Output:
+	// true
+	// false
+	// true
+}
+
+func ExampleVerifyMapKeysMissing() {
+	m := map[string]interface{}{"name": "x", "count": 1.0}
+	fmt.Println(VerifyMapKeysMissing([]string{"name", "count"}, m))
+	fmt.Println(VerifyMapKeysMissing([]string{"size", "name", "id"}, m))
+	// Output:
+	// []
+	// [size id]
+}
+
+func ExampleVerifyMapKeysStringString() {
+	kf := []string{"1", "4"}
+	kt := []string{"1", "2"}
+	m := map[string]string{"1": "one", "2": "two"}
+	fmt.Printf("Missing key:%v\n", VerifyMapKeysStringString(kf, m))
+	fmt.Printf("Contains keys:%v", VerifyMapKeysStringString(kt, m))
+
+	// Output:
+	// Missing key:false
+	// Contains keys:true
+}
+
+func TestBloomFilter(t *testing.T) {
+	const expected = 1000
+	const fpRate = 0.01
+	bf := NewBloomFilter(expected, fpRate)
+	for i := 0; i < expected; i++ {
+		bf.Add([]byte(fmt.Sprintf("member%d", i)))
+	}
+
+	for i := 0; i < expected; i++ {
+		if !bf.MaybeContains([]byte(fmt.Sprintf("member%d", i))) {
+			t.Fatalf("false negative for member%d", i)
+		}
+	}
+
+	falsePositives := 0
+	const trials = 10000
+	for i := 0; i < trials; i++ {
+		if bf.MaybeContains([]byte(fmt.Sprintf("other%d", i))) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / trials; rate > 2*fpRate {
+		t.Errorf("false positive rate too high:%f", rate)
+	}
+}
+
+func TestByteSimilarity(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	a := make([]byte, 4096)
+	r.Read(a)
+
+	// Change a small region.
+	b := append([]byte{}, a...)
+	r.Read(b[1000:1064])
+	score := ByteSimilarity(a, b)
+	if score < 0.8 || score >= 1 {
+		t.Errorf("small change score not correct:%f", score)
+	}
+
+	if score := ByteSimilarity(a, append([]byte{}, a...)); score != 1 {
+		t.Errorf("identical score not correct:%f", score)
+	}
+	unrelated := make([]byte, 4096)
+	r.Read(unrelated)
+	if score := ByteSimilarity(a, unrelated); score > 0.1 {
+		t.Errorf("unrelated score not correct:%f", score)
+	}
+	if ByteSimilarity(nil, nil) != 1 || ByteSimilarity(a, nil) != 0 || ByteSimilarity([]byte("ab"), []byte("ab")) != 1 {
+		t.Error("empty or short input score not correct")
+	}
+}
+
+func TestChain(t *testing.T) {
+	var c Chain
+	if c.Err() != nil {
+		t.Error("empty Chain is not nil")
+	}
+	c.Add(nil)
+	if c.Err() != nil {
+		t.Error("Chain with only nil errors is not nil")
+	}
+
+	_, _, minMaxErr := MinMaxIntSlice([]int{1}, map[int]string{1: ""})
+	_, pathErr := os.Open(filepath.Join(t.TempDir(), "missing"))
+	c.Add(Wrap(minMaxErr, "reading samples"))
+	c.Add(Wrap(pathErr, "reading config"))
+	err := Wrap(c.Err(), "load")
+
+	if !errors.Is(minMaxErr, ErrAllFiltered) {
+		t.Error("errors.Is did not find ErrAllFiltered")
+	}
+	if !errors.Is(err, ErrAllFiltered) || !errors.Is(err, os.ErrNotExist) {
+		t.Error("errors.Is did not find errors in the Chain")
+	}
+	if errors.Is(err, os.ErrPermission) {
+		t.Error("errors.Is found an error not in the Chain")
+	}
+	var pe *os.PathError
+	if !errors.As(err, &pe) || pe != pathErr {
+		t.Error("errors.As did not find the *os.PathError in the Chain")
+	}
+	expected := "load: reading samples: MinMaxIntSlice: all inputs were filtered; reading config: " + pathErr.Error()
+	if err.Error() != expected {
+		t.Errorf("Error not correct, expected:%s, got:%s", expected, err.Error())
+	}
+	if Wrap(nil, "context") != nil {
+		t.Error("Wrap of nil is not nil")
+	}
+}
+
+func TestChunkSlice(t *testing.T) {
+	in := make([]int, 10)
+	for i := range in {
+		in[i] = i
+	}
+	for size := 1; size <= 12; size++ {
+		chunks := ChunkSlice(in, size)
+		if len(chunks) != (len(in)+size-1)/size {
+			t.Errorf("size %d chunk count not correct, got:%d", size, len(chunks))
+		}
+		var joined []int
+		for i, c := range chunks {
+			if len(c) > size || (i < len(chunks)-1 && len(c) != size) {
+				t.Errorf("size %d chunk %d length not correct, got:%d", size, i, len(c))
+			}
+			joined = append(joined, c...)
+		}
+		if !reflect.DeepEqual(joined, in) {
+			t.Errorf("size %d chunks not correct, got:%v", size, chunks)
+		}
+	}
+
+	// Appending to a chunk must not change the next chunk.
+	chunks := ChunkSlice(in, 4)
+	_ = append(chunks[0], -1)
+	if chunks[1][0] != 4 || in[4] != 4 {
+		t.Errorf("append to chunk modified next chunk:%v", chunks)
+	}
+}
+
+func TestChecksumManifest(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+	os.WriteFile(files[0], []byte("admin"), 0600)
+	os.WriteFile(files[1], []byte(""), 0600)
+
+	var buf bytes.Buffer
+	if err := WriteChecksumManifest(files, "sha256", &buf); err != nil {
+		t.Fatalf("WriteChecksumManifest error:%v", err)
+	}
+	expected := "8c6976e5b5410415bde908bd4dee15dfb167a9c873fc4bb8a81f6f2ab448a918  " + files[0] + "\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  " + files[1] + "\n"
+	if buf.String() != expected {
+		t.Errorf("manifest not correct:\n%s", buf.String())
+	}
+
+	m, err := ReadChecksumManifest(&buf)
+	if err != nil {
+		t.Fatalf("ReadChecksumManifest error:%v", err)
+	}
+	if len(m) != 2 || m[files[0]] != "8c6976e5b5410415bde908bd4dee15dfb167a9c873fc4bb8a81f6f2ab448a918" ||
+		m[files[1]] != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("manifest map not correct:%+v", m)
+	}
+
+	missing := filepath.Join(dir, "missing.txt")
+	if err := WriteChecksumManifest([]string{missing}, "sha256", &buf); err == nil ||
+		!strings.Contains(err.Error(), missing) {
+		t.Errorf("missing file error not correct:%v", err)
+	}
+	if err := WriteChecksumManifest(files, "crc", &buf); err == nil {
+		t.Error("unsupported algorithm did not error")
+	}
+	if _, err := ReadChecksumManifest(strings.NewReader("nothex  file\n")); err == nil {
+		t.Error("malformed manifest did not error")
+	}
+}
+
+func TestCompactJSONArraysKeys(t *testing.T) {
+	// Keys, like values, must keep their text and escapes.
+	input := `{"a<b": 1, "caf\u00e9" : {"q\"uote":["x&y"]},"\/slash":"<\u00e9>", "é": []}`
+	expected := `{
+  "a<b": 1,
+  "caf\u00e9": {
+    "q\"uote": ["x&y"]
+  },
+  "\/slash": "<\u00e9>",
+  "é": []
+}`
+	b, err := CompactJSONArrays([]byte(input))
+	if err != nil {
+		t.Fatalf("CompactJSONArrays error:%v", err)
+	}
+	if string(b) != expected {
+		t.Errorf("CompactJSONArrays not correct, expected:\n%s\ngot:\n%s", expected, b)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.bin")
+	data := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(data)
+	if err := os.WriteFile(src, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(src, 0754); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "dst.bin")
+	// Overwrite an existing, longer dst with a different mode.
+	if err := os.WriteFile(dst, append(data, data...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		n, err := CopyFile(src, dst)
+		if err != nil || n != int64(len(data)) {
+			t.Fatalf("CopyFile not correct, expected:%d, got:%d, err:%v", len(data), n, err)
+		}
+		b, err := os.ReadFile(dst)
+		if err != nil || !bytes.Equal(b, data) {
+			t.Errorf("content not correct, err:%v", err)
+		}
+		if fi, err := os.Stat(dst); err != nil || fi.Mode().Perm() != 0754 {
+			t.Errorf("mode not correct, expected:%v, got:%v, err:%v", os.FileMode(0754), fi.Mode().Perm(), err)
+		}
+		os.Remove(dst)
+	}
+
+	if _, err := CopyFile(filepath.Join(dir, "missing"), dst); err == nil {
+		t.Errorf("expected error for missing src")
+	}
+	if _, err := CopyFile(src, filepath.Join(dir, "missing", "dst.bin")); err == nil {
+		t.Errorf("expected error for missing dst directory")
+	}
+
+	// Copying a file onto itself, directly or through a link, must not truncate it.
+	link := filepath.Join(dir, "link.bin")
+	if err := os.Symlink(src, link); err != nil {
+		t.Fatal(err)
+	}
+	for _, same := range []string{src, filepath.Join(dir, ".", "src.bin"), link} {
+		if _, err := CopyFile(src, same); err == nil {
+			t.Errorf("expected error copying to the same file:%s", same)
+		}
+		if b, err := os.ReadFile(src); err != nil || !bytes.Equal(b, data) {
+			t.Errorf("src changed copying to:%s, err:%v", same, err)
+		}
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	long := strings.Repeat("x", 200000)
+	tests := []struct {
+		in       string
+		expected int
+	}{
+		{"", 0},
+		{"\n", 1},
+		{"one", 1},
+		{"one\ntwo\n", 2},
+		{"one\ntwo", 2},
+		{"one\r\ntwo\r\nthree\r\n", 3},
+		{"one\r\ntwo", 2},
+		{"\n\n\n", 3},
+		{long + "\n" + long, 2},
+	}
+	for _, test := range tests {
+		n, err := CountLines(strings.NewReader(test.in))
+		if err != nil || n != test.expected {
+			t.Errorf("CountLines(%.20q) not correct, expected:%d, got:%d, err:%v", test.in, test.expected, n, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := CountFileLines(path); err != nil || n != 3 {
+		t.Errorf("CountFileLines not correct, expected:3, got:%d, err:%v", n, err)
+	}
+	if _, err := CountFileLines(path + ".missing"); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestCreateTempDir(t *testing.T) {
+	path, cleanup, err := CreateTempDir("goutil-test-")
+	if err != nil {
+		t.Fatalf("CreateTempDir error:%v", err)
+	}
+	if !strings.HasPrefix(filepath.Base(path), "goutil-test-") {
+		t.Errorf("path not correct, got:%s", path)
+	}
+	if b, err := DirIsEmpty(path); !b || err != nil {
+		t.Fatalf("directory not created empty, b:%v, err:%v", b, err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "file.txt"), []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := cleanup(); err != nil {
+			t.Errorf("cleanup %d error:%v", i, err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("directory not removed, err:%v", err)
+	}
+}
+
+func TestDeepCopyJSONMap(t *testing.T) {
+	var m map[string]interface{}
+	json.Unmarshal([]byte(`{"server":{"host":"example.com","ports":[80,443]},
+		"users":[{"name":"pat"}],"none":null,"n":1.5}`), &m)
+	original, _ := json.Marshal(m)
+
+	c, err := DeepCopyJSONMap(m)
+	if err != nil {
+		t.Fatalf("DeepCopyJSONMap error:%v", err)
+	}
+	if !reflect.DeepEqual(c, m) {
+		t.Fatalf("copy not correct, expected:%v, got:%v", m, c)
+	}
+
+	c["n"] = 2.0
+	c["none"] = "set"
+	server := c["server"].(map[string]interface{})
+	server["host"] = "changed"
+	server["ports"].([]interface{})[0] = 8080
+	c["users"].([]interface{})[0].(map[string]interface{})["name"] = "lee"
+	delete(c, "users")
+	if after, _ := json.Marshal(m); string(after) != string(original) {
+		t.Errorf("original changed, expected:%s, got:%s", original, after)
+	}
+
+	if c, err := DeepCopyJSONMap(nil); err != nil || c != nil {
+		t.Errorf("nil map not correct, got:%v, err:%v", c, err)
+	}
+	if _, err := DeepCopyJSONMap(map[string]interface{}{"ch": make(chan int)}); err == nil {
+		t.Errorf("expected error for a channel value")
+	}
+	if _, err := DeepCopyJSONMap(map[string]interface{}{"nan": math.NaN()}); err == nil {
+		t.Errorf("expected error for NaN")
+	}
+}
+
+func TestDetectFileContentType(t *testing.T) {
+	dir := t.TempDir()
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 1000)...)
+	files := map[string]struct {
+		data     []byte
+		expected string
+	}{
+		"image.png": {png, "image/png"},
+		"short.txt": {[]byte("hello"), "text/plain; charset=utf-8"},
+		"page.html": {[]byte("<!DOCTYPE html><html></html>"), "text/html; charset=utf-8"},
+		"empty":     {nil, "application/octet-stream"},
+	}
+	for name, f := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, f.data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		ct, err := DetectFileContentType(path)
+		if err != nil || ct != f.expected {
+			t.Errorf("%s not correct, expected:%s, got:%s, err:%v", name, f.expected, ct, err)
+		}
+	}
+	if _, err := DetectFileContentType(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestDirIsEmpty(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	b, err := DirIsEmpty(missing)
+	if b || err == nil {
+		t.Fatalf("missing dir not correct, b:%v, err:%v", b, err)
+	}
+	if !os.IsNotExist(errors.Unwrap(err)) || !strings.Contains(err.Error(), missing) {
+		t.Errorf("error not correct:%v", err)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"HOST": "example.com", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	tests := []struct {
+		in       string
+		expected string
+		err      bool
+	}{
+		{"http://${HOST}/", "http://example.com/", false},
+		{"${PORT:-8080}", "8080", false},
+		{"${HOST:-other}", "example.com", false},
+		{"${EMPTY:-default}", "default", false},
+		{"${EMPTY}", "", false},
+		{"${PORT:-}", "", false},
+		{"cost $$5 $HOST $", "cost $5 $HOST $", false},
+		{"$${HOST}", "${HOST}", false},
+		{"${PORT}", "", true},
+		{"${HOST", "", true},
+	}
+	for _, test := range tests {
+		got, err := ExpandEnv(test.in, lookup)
+		if (err != nil) != test.err || got != test.expected {
+			t.Errorf("ExpandEnv(%q) not correct, expected:%q, err:%v, got:%q, err:%v",
+				test.in, test.expected, test.err, got, err)
+		}
+	}
+
+	t.Setenv("GOUTIL_EXPAND_ENV", "set")
+	if got, err := ExpandEnv("${GOUTIL_EXPAND_ENV}", nil); err != nil || got != "set" {
+		t.Errorf("ExpandEnv with nil lookup not correct, got:%q, err:%v", got, err)
+	}
+}
+
+func TestHashRing(t *testing.T) {
+	hr := NewHashRing(100)
+	if n := hr.GetNode("key"); n != "" {
+		t.Errorf("empty ring returned node:%s", n)
+	}
+
+	for _, n := range []string{"node1", "node2", "node3"} {
+		hr.AddNode(n)
+	}
+	before := map[string]string{}
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("key%d", i)
+		before[k] = hr.GetNode(k)
+	}
+
+	// Adding a node must only move keys to the new node.
+	hr.AddNode("node4")
+	moved := 0
+	for k, n := range before {
+		after := hr.GetNode(k)
+		if after != n {
+			moved++
+			if after != "node4" {
+				t.Errorf("key %s moved from %s to %s, not the new node", k, n, after)
+			}
+		}
+	}
+	if moved == 0 || moved > len(before)/2 {
+		t.Errorf("unexpected number of keys moved:%d", moved)
+	}
+
+	// Removing the node must restore the original mapping.
+	hr.RemoveNode("node4")
+	for k, n := range before {
+		if after := hr.GetNode(k); after != n {
+			t.Errorf("key %s mapped to %s after removal, expected %s", k, after, n)
+		}
+	}
+
+	// node2578 and node46715 have the same hash for their only virtual node; each must
+	// keep a position, and removing one must not remove the other.
+	for _, remove := range []string{"node2578", "node46715"} {
+		hr := NewHashRing(1)
+		hr.AddNode("node2578")
+		hr.AddNode("node46715")
+		if len(hr.hashes) != 2 {
+			t.Fatalf("colliding nodes not both on the ring, hashes:%v", hr.hashes)
+		}
+		hr.RemoveNode(remove)
+		expected := "node2578"
+		if remove == expected {
+			expected = "node46715"
+		}
+		if n := hr.GetNode("key"); n != expected {
+			t.Errorf("after removing %s, GetNode not correct, expected:%s, got:%s", remove, expected, n)
+		}
+	}
+}
+
+func TestHumanizeBytesParseBytes(t *testing.T) {
+	// Sizes that are exact to one decimal in their unit round trip.
+	for _, n := range []int64{0, 1, 1023, 1024, 1536, 10 << 20, 3 << 30, 1 << 40, -1536, 7 << 60} {
+		got, err := ParseBytes(HumanizeBytes(n))
+		if err != nil || got != n {
+			t.Errorf("round trip of %d not correct, got:%d, err:%v", n, got, err)
+		}
+	}
+	// Other sizes round trip to within the rounding of one decimal.
+	for _, n := range []int64{1100, 123456789, 999999999999} {
+		got, err := ParseBytes(HumanizeBytes(n))
+		if err != nil || math.Abs(float64(got-n))/float64(n) > 0.05 {
+			t.Errorf("round trip of %d not correct, got:%d, err:%v", n, got, err)
+		}
+	}
+
+	units := map[string]int64{
+		"1B": 1, "1 KB": 1000, "1 kib": 1024, "1MB": 1000000, "1 MiB": 1 << 20,
+		"1 GB": 1e9, "1 GiB": 1 << 30, "1 TB": 1e12, "1 TiB": 1 << 40,
+		"1 PB": 1e15, "1 PiB": 1 << 50, "1 EB": 1e18, "1 EiB": 1 << 60,
+	}
+	for s, expected := range units {
+		if got, err := ParseBytes(s); err != nil || got != expected {
+			t.Errorf("ParseBytes(%q) not correct, expected:%d, got:%d, err:%v", s, expected, got, err)
+		}
+	}
+	for _, s := range []string{"", "1.2.3 KB", "1 K", "8 EiB", "10 EB", "abc"} {
+		if _, err := ParseBytes(s); err == nil {
+			t.Errorf("ParseBytes(%q) expected error", s)
+		}
+	}
+}
+
+func TestIndentMinifyJSON(t *testing.T) {
+	tests := []string{
+		`{"z":1,"a":{"m":[1,2.50,-3e4],"b":"x \" y"},"k":[]}`,
+		`[ {"a" : null}, true, "s" ]`,
+		`"just a string"`,
+	}
+	for _, in := range tests {
+		var expected bytes.Buffer
+		if err := json.Compact(&expected, []byte(in)); err != nil {
+			t.Fatalf("json.Compact error:%v", err)
+		}
+		indented, err := IndentJSON([]byte(in), "  ")
+		if err != nil {
+			t.Fatalf("IndentJSON error:%v", err)
+		}
+		minified, err := MinifyJSON(indented)
+		if err != nil {
+			t.Fatalf("MinifyJSON error:%v", err)
+		}
+		if !bytes.Equal(minified, expected.Bytes()) {
+			t.Errorf("round trip not correct, expected:%s, got:%s", expected.Bytes(), minified)
+		}
+	}
+}
+
+func TestLevenshteinDistance(t *testing.T) {
+	// Compare with the number of EditOperations over random strings.
+	r := rand.New(rand.NewSource(1))
+	randString := func() string {
+		runes := []rune("abcé日")
+		out := make([]rune, r.Intn(8))
+		for i := range out {
+			out[i] = runes[r.Intn(len(runes))]
+		}
+		return string(out)
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randString(), randString()
+		d := LevenshteinDistance(a, b)
+		if d != len(EditOperations(a, b)) {
+			t.Errorf("LevenshteinDistance(%q, %q) not correct, expected:%d, got:%d", a, b, len(EditOperations(a, b)), d)
+		}
+		if d != LevenshteinDistance(b, a) {
+			t.Errorf("LevenshteinDistance(%q, %q) not symmetric", a, b)
+		}
+	}
+}
+
+func TestMapGet(t *testing.T) {
+	var m map[string]interface{}
+	json.Unmarshal([]byte(`{"name":"pat","count":42,"ratio":0.5,"big":1e300,"neg":-3,
+		"admin":true,"nothing":null,"list":[1]}`), &m)
+	m["int"] = 7
+	m["number"] = json.Number("2e3")
+
+	if v, ok := MapGetString(m, "name"); !ok || v != "pat" {
+		t.Errorf("MapGetString not correct, got:%v, ok:%v", v, ok)
+	}
+	if v, ok := MapGetBool(m, "admin"); !ok || !v {
+		t.Errorf("MapGetBool not correct, got:%v, ok:%v", v, ok)
+	}
+	if v, ok := MapGetFloat(m, "ratio"); !ok || v != 0.5 {
+		t.Errorf("MapGetFloat not correct, got:%v, ok:%v", v, ok)
+	}
+	if v, ok := MapGetFloat(m, "int"); !ok || v != 7 {
+		t.Errorf("MapGetFloat int not correct, got:%v, ok:%v", v, ok)
+	}
+	for key, expected := range map[string]int{"count": 42, "neg": -3, "int": 7, "number": 2000} {
+		if v, ok := MapGetInt(m, key); !ok || v != expected {
+			t.Errorf("MapGetInt %s not correct, expected:%d, got:%v, ok:%v", key, expected, v, ok)
+		}
+	}
+
+	// Missing keys, null, wrong types, and floats that are not ints are not ok.
+	for _, key := range []string{"missing", "nothing", "list", "count", "ratio"} {
+		if _, ok := MapGetString(m, key); ok {
+			t.Errorf("MapGetString %s expected not ok", key)
+		}
+	}
+	for _, key := range []string{"missing", "nothing", "name", "count"} {
+		if _, ok := MapGetBool(m, key); ok {
+			t.Errorf("MapGetBool %s expected not ok", key)
+		}
+	}
+	for _, key := range []string{"missing", "nothing", "name", "admin"} {
+		if _, ok := MapGetFloat(m, key); ok {
+			t.Errorf("MapGetFloat %s expected not ok", key)
+		}
+	}
+	for _, key := range []string{"missing", "nothing", "name", "admin", "ratio", "big", "list"} {
+		if _, ok := MapGetInt(m, key); ok {
+			t.Errorf("MapGetInt %s expected not ok", key)
+		}
+	}
+	if _, ok := MapGetInt(nil, "count"); ok {
+		t.Errorf("MapGetInt nil map expected not ok")
+	}
+}
+
+func TestMerkleProof(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		blocks := make([][]byte, n)
+		for i := range blocks {
+			blocks[i] = []byte(fmt.Sprintf("block %d", i))
+		}
+		root, err := MerkleRoot(blocks)
+		if err != nil {
+			t.Fatalf("MerkleRoot error:%v", err)
+		}
+		for i := range blocks {
+			proof, err := MerkleProof(blocks, i)
+			if err != nil {
+				t.Fatalf("MerkleProof error:%v", err)
+			}
+			if !VerifyMerkleProof(blocks[i], i, n, proof, root) {
+				t.Errorf("%d blocks, proof for index %d did not verify", n, i)
+			}
+			if VerifyMerkleProof([]byte("tampered"), i, n, proof, root) {
+				t.Errorf("%d blocks, tampered block at index %d verified", n, i)
+			}
+			if j := i ^ 1; VerifyMerkleProof(blocks[i], j, n, proof, root) {
+				t.Errorf("%d blocks, proof for index %d verified at index %d", n, i, j)
+			}
+			if VerifyMerkleProof(blocks[i], i+n, n, proof, root) {
+				t.Errorf("%d blocks, proof for index %d verified at index %d", n, i, i+n)
+			}
+			if VerifyMerkleProof(blocks[i], -1, n, proof, root) {
+				t.Errorf("%d blocks, proof for index %d verified at index -1", n, i)
+			}
+			if VerifyMerkleProof(blocks[i], i, n, append(proof, root), root) {
+				t.Errorf("%d blocks, proof for index %d verified with an extra hash", n, i)
+			}
+			if len(proof) > 0 && VerifyMerkleProof(blocks[i], i, n, proof[:len(proof)-1], root) {
+				t.Errorf("%d blocks, proof for index %d verified with a missing hash", n, i)
+			}
+		}
+
+		changed := append([][]byte{}, blocks...)
+		changed[n-1] = []byte("changed")
+		if other, _ := MerkleRoot(changed); bytes.Equal(root, other) {
+			t.Errorf("%d blocks, root did not change with a block", n)
+		}
+	}
+
+	// Two blocks: the root is the node hash of the two leaf hashes.
+	leaf := func(b string) []byte {
+		s := sha256.Sum256(append([]byte{0x00}, b...))
+		return s[:]
+	}
+	expected := sha256.Sum256(append(append([]byte{0x01}, leaf("a")...), leaf("b")...))
+	if root, _ := MerkleRoot([][]byte{[]byte("a"), []byte("b")}); !bytes.Equal(root, expected[:]) {
+		t.Errorf("root not correct, expected:%x, got:%x", expected, root)
+	}
+
+	if _, err := MerkleRoot(nil); err == nil {
+		t.Errorf("MerkleRoot expected error for no blocks")
+	}
+	for _, index := range []int{-1, 2} {
+		if _, err := MerkleProof([][]byte{[]byte("a"), []byte("b")}, index); err == nil {
+			t.Errorf("MerkleProof expected error for index:%d", index)
+		}
+	}
+}
+
+func TestMultiReaderSep(t *testing.T) {
+	sep := []byte("\n--\n")
+	tests := []struct {
+		readers  []string
+		expected string
+	}{
+		{[]string{"one", "two", "three"}, "one\n--\ntwo\n--\nthree"},
+		{[]string{"only"}, "only"},
+		{[]string{"", "b"}, "\n--\nb"},
+		{nil, ""},
+	}
+	for _, test := range tests {
+		readers := []io.Reader{}
+		for _, s := range test.readers {
+			readers = append(readers, strings.NewReader(s))
+		}
+		b, err := io.ReadAll(MultiReaderSep(sep, readers...))
+		if err != nil {
+			t.Fatalf("read error:%v", err)
+		}
+		if string(b) != test.expected {
+			t.Errorf("MultiReaderSep not correct, expected:%q, got:%q", test.expected, b)
+		}
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	// NaturalLess must be a strict ordering for sort to be reliable.
+	values := []string{"", "a", "a1", "a01", "a001", "a2", "a10", "a1b", "a1b2", "a1b10", "b", "é1", "10", "9",
+		"99999999999999999999999", "100000000000000000000000", "x0", "x00"}
+	for _, a := range values {
+		if NaturalLess(a, a) {
+			t.Errorf("NaturalLess(%q, %q) is true", a, a)
+		}
+		for _, b := range values {
+			if a != b && NaturalLess(a, b) == NaturalLess(b, a) {
+				t.Errorf("NaturalLess(%q, %q) not antisymmetric", a, b)
+			}
+			for _, c := range values {
+				if NaturalLess(a, b) && NaturalLess(b, c) && !NaturalLess(a, c) {
+					t.Errorf("NaturalLess not transitive for %q, %q, %q", a, b, c)
+				}
+			}
+		}
+	}
+}
+
+func TestPrettyJSONStrings(t *testing.T) {
+	tests := []string{
+		"{\"note\":\"1,\n2,\n3\"}",
+		`{"note":"1,\n2,\n3"}`,
+		"{\n  \"note\": \"[ 1,\n 2 ]\",\n  \"esc\": \"a \\\" 4,\n 5 \\\\\"\n}",
+	}
+	for _, in := range tests {
+		if out := string(PrettyJSON([]byte(in))); out != in {
+			t.Errorf("PrettyJSON changed string content, in:%q, out:%q", in, out)
+		}
+	}
+
+	in := "{\n  \"note\": \"1,\n2,\n3\",\n  \"values\": [\n    1,\n    2\n  ]\n}"
+	expected := "{\n  \"note\": \"1,\n2,\n3\",\n  \"values\": [1,2]\n}"
+	if out := string(PrettyJSON([]byte(in))); out != expected {
+		t.Errorf("PrettyJSON not correct, expected:%q, got:%q", expected, out)
+	}
+}
+
+func TestRequestBearerToken(t *testing.T) {
+	tests := []struct {
+		auth  []string
+		token string
+		ok    bool
+	}{
+		{[]string{"Bearer abc.def.ghi"}, "abc.def.ghi", true},
+		{[]string{"bearer abc"}, "abc", true},
+		{[]string{"  BEARER \t abc  "}, "abc", true},
+		{[]string{"Basic dGVzdDp0ZXN0", "Bearer abc"}, "abc", true},
+		{[]string{"Bearer"}, "", false},
+		{[]string{"Bearer a b"}, "", false},
+		{[]string{"Bearerabc"}, "", false},
+		{[]string{"Basic dGVzdDp0ZXN0"}, "", false},
+		{nil, "", false},
+	}
+	for _, test := range tests {
+		var token string
+		var ok bool
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok = RequestBearerToken(r)
+		})
+		req := httptest.NewRequest(http.MethodGet, "http://TestRequestBearerToken", nil)
+		for _, a := range test.auth {
+			req.Header.Add("Authorization", a)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if token != test.token || ok != test.ok {
+			t.Errorf("%q not correct, expected:%q, %v, got:%q, %v", test.auth, test.token, test.ok, token, ok)
+		}
+	}
+}
+
+func TestRequestQuery(t *testing.T) {
+	var query map[string][]string
+	var first, empty, missing string
+	var firstOK, emptyOK, missingOK bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = RequestQueryMap(r)
+		first, firstOK = RequestQueryFirst(r, "id")
+		empty, emptyOK = RequestQueryFirst(r, "flag")
+		missing, missingOK = RequestQueryFirst(r, "missing")
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://TestRequestQuery/?id=1&tag=a&id=2&flag&name=J%C3%A4s%C3%B8n", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	expected := map[string][]string{"id": {"1", "2"}, "tag": {"a"}, "flag": {""}, "name": {"Jäsøn"}}
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("RequestQueryMap not correct, expected:%v, got:%v", expected, query)
+	}
+	if first != "1" || !firstOK {
+		t.Errorf("repeated key not correct, got:%q, ok:%v", first, firstOK)
+	}
+	if empty != "" || !emptyOK {
+		t.Errorf("empty value not correct, got:%q, ok:%v", empty, emptyOK)
+	}
+	if missing != "" || missingOK {
+		t.Errorf("missing key not correct, got:%q, ok:%v", missing, missingOK)
+	}
+}
+
+func TestRequestRemoteIP(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		xff        string
+		trust      bool
+		expected   string
+	}{
+		{"192.0.2.1:1234", "", false, "192.0.2.1"},
+		{"192.0.2.1:1234", "", true, "192.0.2.1"},
+		{"[2001:db8::1]:1234", "", false, "2001:db8::1"},
+		{"192.0.2.1", "", false, "192.0.2.1"},
+		{"10.0.0.1:1234", "203.0.113.7, 10.0.0.2, 10.0.0.1", true, "203.0.113.7"},
+		{"10.0.0.1:1234", "203.0.113.7, 10.0.0.2", false, "10.0.0.1"},
+		{"10.0.0.1:1234", " 2001:db8::2 ,10.0.0.2", true, "2001:db8::2"},
+		{"10.0.0.1:1234", "[2001:db8::3]:443", true, "2001:db8::3"},
+		{"10.0.0.1:1234", "203.0.113.7:8080", true, "203.0.113.7"},
+		{"10.0.0.1:1234", " , 10.0.0.2", true, "10.0.0.1"},
+	}
+	for _, test := range tests {
+		var ip string
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip = RequestRemoteIP(r, test.trust)
+		})
+		req := httptest.NewRequest(http.MethodGet, "http://TestRequestRemoteIP", nil)
+		req.RemoteAddr = test.remoteAddr
+		if test.xff != "" {
+			req.Header.Set("X-Forwarded-For", test.xff)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if ip != test.expected {
+			t.Errorf("%s, %q, %v not correct, expected:%s, got:%s", test.remoteAddr, test.xff, test.trust, test.expected, ip)
+		}
+	}
+}
+
+func TestRequestUsername(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(testHandlerFuncUser))
+	defer ts.Close()
+
+	handler := http.HandlerFunc(testHandlerFuncUser)
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "http://TestRequestUsername", nil)
+	req.SetBasicAuth("testUser", "password")
+	handler.ServeHTTP(resp, req)
+
+	if reqUser != "testUser" {
+		t.Errorf("User was not correct, reqUser:%+v", reqUser)
+	} else {
+		//fmt.Printf("User was:%+v", reqUser)
+	}
+}
+
+func TestRequestUsernameDigest(t *testing.T) {
+	handler := http.HandlerFunc(testHandlerFuncUser)
+	tests := []struct {
+		auth string
+		user string
+	}{
+		{`Digest username="admin", realm="Western Digital Corporation", nonce="AHYBbBIPrPRMzsDo", uri="/", response="6629fae49393a05397450978507c4ef1"`, "admin"},
+		// username after realm and nonce.
+		{`Digest realm="testrealm@host.com", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", uri="/dir/index.html", qop=auth, nc=00000001, cnonce="0a4f113b", username="Mufasa", response="6629fae49393a05397450978507c4ef1"`, "Mufasa"},
+		// Quoted username containing a comma, "=", and an escaped quote.
+		{`Digest realm="r", username="Doe, \"J\"=x", nonce="abc"`, `Doe, "J"=x`},
+		// userhash=true; the hashed username, which may contain "=", is returned.
+		{`Digest username="488869477bf257147b804c45308cd62ac4e25eb717b12b298c79e62dcea254ec", realm="api@example.org", uri="/doe.json", algorithm=SHA-256, userhash=true, nonce="5TsQWLVdgBdmrQ=="`, "488869477bf257147b804c45308cd62ac4e25eb717b12b298c79e62dcea254ec"},
+		// Unquoted username and lower case scheme.
+		{`digest nonce=abc, username=bob`, "bob"},
+		// RFC 7616 extended notation.
+		{`Digest username*=UTF-8''J%C3%A4s%C3%B8n%20Doe, realm="api@example.org"`, "Jäsøn Doe"},
+		{`Digest realm="no user", nonce="abc"`, ""},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "http://TestRequestUsernameDigest", nil)
+		req.Header.Set("Authorization", test.auth)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if reqUser != test.user {
+			t.Errorf("User was not correct, reqUser:%+v, expected:%+v", reqUser, test.user)
+		}
+	}
+}
+
+func TestRequestUsernameBearer(t *testing.T) {
+	// Unsigned JWT; header {"alg":"none","typ":"JWT"},
+	// payload {"sub":"testUser","preferred_username":"tëst?>>","admin":true}
+	jwt := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." +
+		"eyJzdWIiOiJ0ZXN0VXNlciIsInByZWZlcnJlZF91c2VybmFtZSI6InTDq3N0Pz4-IiwiYWRtaW4iOnRydWV9."
+
+	var user string
+	var err error
+	claim := "sub"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err = RequestUsernameBearer(r, claim)
+	})
+
+	tests := []struct {
+		auth    string
+		claim   string
+		user    string
+		wantErr bool
+	}{
+		{"Bearer " + jwt, "sub", "testUser", false},
+		{"bearer  " + jwt, "preferred_username", "tëst?>>", false},
+		{"Bearer " + jwt, "email", "", true},
+		{"Bearer " + jwt, "admin", "", true},
+		{"Bearer not.a-jwt", "sub", "", true},
+		{"Bearer onlyone", "sub", "", true},
+		{"Basic YWRtaW46YWRtaW4=", "sub", "", true},
+		{"", "sub", "", true},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "http://TestRequestUsernameBearer", nil)
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		claim = test.claim
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if user != test.user || (err != nil) != test.wantErr {
+			t.Errorf("auth:%s, claim:%s, user:%s, err:%v", test.auth, test.claim, user, err)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errFail := errors.New("fail")
+	failUntil := func(calls *int, success int) func() error {
+		return func() error {
+			*calls++
+			if *calls < success {
+				return errFail
+			}
+			return nil
+		}
+	}
+
+	// Success on the first try does not wait.
+	calls := 0
+	start := time.Now()
+	if err := Retry(5, time.Hour, failUntil(&calls, 1)); err != nil || calls != 1 {
+		t.Errorf("first try not correct, calls:%d, err:%v", calls, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("first try waited")
+	}
+
+	// Stops on success, with waits of 1, 2, and 4ms between attempts.
+	calls = 0
+	start = time.Now()
+	if err := Retry(10, time.Millisecond, failUntil(&calls, 4)); err != nil || calls != 4 {
+		t.Errorf("stop on success not correct, calls:%d, err:%v", calls, err)
+	}
+	if elapsed := time.Since(start); elapsed < 7*time.Millisecond {
+		t.Errorf("backoff not correct, elapsed:%v", elapsed)
+	}
+
+	calls = 0
+	err := Retry(3, time.Millisecond, failUntil(&calls, 100))
+	if calls != 3 || !errors.Is(err, errFail) || err.Error() != "Retry: 3 attempts: fail" {
+		t.Errorf("all attempts failing not correct, calls:%d, err:%v", calls, err)
+	}
+	calls = 0
+	if err := Retry(0, time.Millisecond, failUntil(&calls, 100)); calls != 1 || !errors.Is(err, errFail) {
+		t.Errorf("attempts <= 0 not correct, calls:%d, err:%v", calls, err)
+	}
+
+	// Cancellation while waiting to retry.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	calls = 0
+	err = RetryContext(ctx, 10, time.Hour, failUntil(&calls, 100))
+	if calls != 1 || !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "fail") {
+		t.Errorf("cancellation not correct, calls:%d, err:%v", calls, err)
+	}
+	calls = 0
+	if err := RetryContext(ctx, 10, time.Millisecond, failUntil(&calls, 1)); calls != 0 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("done context not correct, calls:%d, err:%v", calls, err)
+	}
+}
+
+// slowReader returns one byte of data per Read, after a delay.
+type slowReader struct {
+	data  []byte
+	delay time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	if len(s.data) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(s.delay)
+	n := copy(p[:1], s.data)
+	s.data = s.data[n:]
+	return n, nil
+}
+
+func TestSHA1ChecksumReaderContext(t *testing.T) {
+	data := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(data)
+	sum, err := SHA1ChecksumReaderContext(context.Background(), bytes.NewReader(data))
+	if err != nil || sum != SHA1Checksum(data) {
+		t.Errorf("checksum not correct, expected:%x, got:%x, err:%v", SHA1Checksum(data), sum, err)
+	}
+	sum, err = SHA1ChecksumReaderContext(context.Background(), &slowReader{data: []byte("abc")})
+	if err != nil || sum != SHA1Checksum([]byte("abc")) {
+		t.Errorf("chunked checksum not correct, got:%x, err:%v", sum, err)
+	}
+
+	// Cancel mid-stream; reading all of the data would take 100s.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = SHA1ChecksumReaderContext(ctx, &slowReader{data: data[:10000], delay: 10 * time.Millisecond})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("cancel not correct, err:%v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancel did not stop reading, elapsed:%v", elapsed)
+	}
+
+	// Already cancelled; nothing is read.
+	r := &slowReader{data: []byte("abc")}
+	if _, err := SHA1ChecksumReaderContext(ctx, r); !errors.Is(err, context.Canceled) || len(r.data) != 3 {
+		t.Errorf("cancelled context not correct, unread:%d, err:%v", len(r.data), err)
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	const limit = 3
+	s := NewSemaphore(limit)
+	var holders, maxHolders int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Acquire(1); err != nil {
+				t.Errorf("Acquire error:%v", err)
+				return
+			}
+			defer s.Release(1)
+
+			h := atomic.AddInt32(&holders, 1)
+			for {
+				m := atomic.LoadInt32(&maxHolders)
+				if h <= m || atomic.CompareAndSwapInt32(&maxHolders, m, h) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+		}()
+	}
+	wg.Wait()
+	if maxHolders > limit || maxHolders == 0 {
+		t.Errorf("max holders not correct:%d", maxHolders)
+	}
+
+	if err := s.Acquire(limit + 1); err == nil {
+		t.Error("Acquire larger than size did not error")
+	}
+	if !s.TryAcquire(2) {
+		t.Error("TryAcquire(2) failed on an idle semaphore")
+	}
+	if s.TryAcquire(2) {
+		t.Error("TryAcquire(2) succeeded with only 1 slot free")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.AcquireContext(ctx, 2); err != context.DeadlineExceeded {
+		t.Errorf("AcquireContext error not correct:%v", err)
+	}
+	// The failed acquisitions must not hold any slots.
+	s.Release(2)
+	if !s.TryAcquire(limit) {
+		t.Error("slots were leaked by failed acquisitions")
+	}
+}
+
+func TestSet(t *testing.T) {
+	sorted := func(s Set[int]) []int {
+		out := s.Slice()
+		sort.Ints(out)
+		return out
+	}
+
+	a := NewSet(1, 2, 3, 3, 4)
+	b := NewSet(3, 4, 5)
+	if a.Len() != 4 || !reflect.DeepEqual(sorted(a), []int{1, 2, 3, 4}) {
+		t.Errorf("NewSet not correct, got:%v", sorted(a))
+	}
+	if !a.Contains(1) || a.Contains(5) {
+		t.Errorf("Contains not correct")
+	}
+	if got := sorted(a.Union(b)); !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Union not correct, got:%v", got)
+	}
+	if got := sorted(a.Intersect(b)); !reflect.DeepEqual(got, []int{3, 4}) {
+		t.Errorf("Intersect not correct, got:%v", got)
+	}
+	if got := sorted(a.Difference(b)); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("Difference not correct, got:%v", got)
+	}
+	if got := sorted(b.Difference(a)); !reflect.DeepEqual(got, []int{5}) {
+		t.Errorf("Difference not correct, got:%v", got)
+	}
+	// Operands are not modified.
+	if !reflect.DeepEqual(sorted(a), []int{1, 2, 3, 4}) || !reflect.DeepEqual(sorted(b), []int{3, 4, 5}) {
+		t.Errorf("operands modified, a:%v, b:%v", sorted(a), sorted(b))
+	}
+
+	a.Add(9, 10)
+	a.Remove(1, 2, 42)
+	if got := sorted(a); !reflect.DeepEqual(got, []int{3, 4, 9, 10}) {
+		t.Errorf("Add/Remove not correct, got:%v", got)
+	}
+
+	var empty Set[string]
+	if empty.Len() != 0 || empty.Contains("a") || len(empty.Slice()) != 0 || empty.Slice() == nil {
+		t.Errorf("nil Set not correct")
+	}
+	if got := NewSet[string]().Union(NewSet("a")).Slice(); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("string Set not correct, got:%v", got)
+	}
+}
+
+func TestStatusRecorder(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter)
+		status  int
+	}{
+		{"write only", func(w http.ResponseWriter) { w.Write([]byte("ok")) }, http.StatusOK},
+		{"nothing written", func(w http.ResponseWriter) {}, http.StatusOK},
+		{"not found", func(w http.ResponseWriter) { http.Error(w, "missing", http.StatusNotFound) }, http.StatusNotFound},
+		{"first WriteHeader", func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusCreated)
+			w.WriteHeader(http.StatusInternalServerError)
+		}, http.StatusCreated},
+		{"write then WriteHeader", func(w http.ResponseWriter) {
+			w.Write([]byte("ok"))
+			w.WriteHeader(http.StatusTeapot)
+		}, http.StatusOK},
+	}
+	for _, test := range tests {
+		var sr *StatusRecorder
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sr = NewStatusRecorder(w)
+			sr.Header().Set("X-Test", test.name)
+			test.handler(sr)
+		})
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "http://TestStatusRecorder", nil))
+		if sr.Status() != test.status {
+			t.Errorf("%s status not correct, expected:%d, got:%d", test.name, test.status, sr.Status())
+		}
+		if resp.Code != test.status || resp.Header().Get("X-Test") != test.name {
+			t.Errorf("%s response not correct, code:%d, header:%q", test.name, resp.Code, resp.Header().Get("X-Test"))
+		}
+		if sr.Unwrap() != http.ResponseWriter(resp) {
+			t.Errorf("%s Unwrap not correct", test.name)
+		}
+	}
+
+	// 1xx informational responses are not the final status.
+	sr := NewStatusRecorder(httptest.NewRecorder())
+	sr.WriteHeader(http.StatusEarlyHints)
+	sr.WriteHeader(http.StatusAccepted)
+	if sr.Status() != http.StatusAccepted {
+		t.Errorf("informational status not correct, got:%d", sr.Status())
+	}
+}
+
+func TestStringToByteSlice(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		in := make([]byte, r.Intn(100))
+		r.Read(in)
+		bytesPerLine := r.Intn(20)
+		out, err := StringToByteSlice(ByteSliceToString(in, bytesPerLine))
+		if err != nil {
+			t.Fatalf("StringToByteSlice error:%v", err)
+		}
+		if !bytes.Equal(in, out) {
+			t.Errorf("round trip failed, bytesPerLine:%d\nin: %v\nout:%v", bytesPerLine, in, out)
+		}
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	text := "{\"name\": \"café 日本 🙂\", \"n\": 1}\n"
+	utf16Bytes := func(order binary.AppendByteOrder) []byte {
+		var b []byte
+		for _, u := range utf16.Encode([]rune(text)) {
+			b = order.AppendUint16(b, u)
+		}
+		return b
+	}
+	inputs := map[string][]byte{
+		"UTF-8":              []byte(text),
+		"UTF-8 BOM":          append([]byte{0xef, 0xbb, 0xbf}, text...),
+		"UTF-16LE BOM":       append([]byte{0xff, 0xfe}, utf16Bytes(binary.LittleEndian)...),
+		"UTF-16BE BOM":       append([]byte{0xfe, 0xff}, utf16Bytes(binary.BigEndian)...),
+		"UTF-16LE heuristic": utf16Bytes(binary.LittleEndian),
+		"UTF-16BE heuristic": utf16Bytes(binary.BigEndian),
+	}
+	for name, in := range inputs {
+		out, err := ToUTF8(in)
+		if err != nil {
+			t.Errorf("%s error:%v", name, err)
+			continue
+		}
+		if string(out) != text {
+			t.Errorf("%s not correct, expected:%q, got:%q", name, text, out)
+		}
+	}
+
+	if out, err := ToUTF8(nil); err != nil || len(out) != 0 {
+		t.Errorf("empty input not correct, got:%q, err:%v", out, err)
+	}
+
+	bad := map[string][]byte{
+		"binary":             {0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, 0, 0, 0, 0x0d, 0xff},
+		"invalid UTF-8 BOM":  {0xef, 0xbb, 0xbf, 'a', 0xff},
+		"odd length UTF-16":  {0xff, 0xfe, 'a', 0, 'b'},
+		"unpaired surrogate": {0xff, 0xfe, 'a', 0, 0x3d, 0xd8, 'b', 0},
+		"UTF-16 with NUL":    {0xff, 0xfe, 'a', 0, 0, 0},
+	}
+	for name, in := range bad {
+		if _, err := ToUTF8(in); err == nil {
+			t.Errorf("%s expected error", name)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	const rate = 100
+	const burst = 5
+	tb := NewTokenBucket(rate, burst)
+	for i := 0; i < burst; i++ {
+		if !tb.Allow() {
+			t.Fatalf("burst Allow %d failed", i)
+		}
+	}
+	if tb.Allow() {
+		t.Fatal("Allow succeeded with an empty bucket")
+	}
+
+	// Steady state; waiting for n tokens from empty takes about n/rate seconds.
+	const n = 10
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		if err := tb.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait error:%v", err)
+		}
+	}
+	elapsed := time.Since(start)
+	expected := n * time.Second / rate
+	if elapsed < expected*8/10 || elapsed > expected*5 {
+		t.Errorf("steady state rate not correct, elapsed:%v, expected:%v", elapsed, expected)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewTokenBucket(0, 1).Wait(ctx); err != nil {
+		t.Errorf("Wait with a token available failed:%v", err)
+	}
+	empty := NewTokenBucket(0, 1)
+	empty.Allow()
+	if err := empty.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait error not correct:%v", err)
+	}
+}
+
+func TestTransformCSV(t *testing.T) {
+	in := "name,qty\nbolt,2\n\"nut, hex\",5\nwasher,10\n"
+	double := func(row []string) ([]string, error) {
+		n, err := strconv.Atoi(row[1])
+		if err != nil {
+			return nil, err
+		}
+		return []string{row[0], strconv.Itoa(2 * n)}, nil
+	}
+
+	var out bytes.Buffer
+	if err := TransformCSV(strings.NewReader(in), &out, double, true); err != nil {
+		t.Fatalf("TransformCSV error:%v", err)
+	}
+	expected := "name,qty\nbolt,4\n\"nut, hex\",10\nwasher,20\n"
+	if out.String() != expected {
+		t.Errorf("TransformCSV not correct, expected:%q, got:%q", expected, out.String())
+	}
+
+	// Without keepHeader the header is transformed, and fails to parse.
+	out.Reset()
+	err := TransformCSV(strings.NewReader(in), &out, double, false)
+	if err == nil || !strings.Contains(err.Error(), "row 1") {
+		t.Errorf("TransformCSV error not correct:%v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("TransformCSV wrote output after an error:%q", out.String())
+	}
+
+	// A nil row is dropped.
+	out.Reset()
+	dropSmall := func(row []string) ([]string, error) {
+		if n, _ := strconv.Atoi(row[1]); n < 5 {
+			return nil, nil
+		}
+		return row, nil
+	}
+	if err := TransformCSV(strings.NewReader(in), &out, dropSmall, true); err != nil {
+		t.Fatalf("TransformCSV error:%v", err)
+	}
+	expected = "name,qty\n\"nut, hex\",5\nwasher,10\n"
+	if out.String() != expected {
+		t.Errorf("TransformCSV not correct, expected:%q, got:%q", expected, out.String())
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	s := "aé日🙂b"
+	for max := -1; max <= 6; max++ {
+		for _, ellipsis := range []string{"", ".", "…", "..."} {
+			out := Truncate(s, max, ellipsis)
+			if !utf8.ValidString(out) {
+				t.Errorf("Truncate(%q, %d, %q) is not valid UTF-8:%q", s, max, ellipsis, out)
+			}
+			limit := max
+			if limit < 0 {
+				limit = 0
+			}
+			if n := utf8.RuneCountInString(out); n > limit && out != s {
+				t.Errorf("Truncate(%q, %d, %q) too long:%q", s, max, ellipsis, out)
+			}
+		}
+	}
+}
+
+func TestUUIDv4(t *testing.T) {
+	format := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := map[string]struct{}{}
+	for i := 0; i < 1000; i++ {
+		u, err := UUIDv4()
+		if err != nil {
+			t.Fatalf("UUIDv4 error:%v", err)
+		}
+		if !format.MatchString(u) {
+			t.Errorf("UUIDv4 format not correct:%s", u)
+		}
+		if _, ok := seen[u]; ok {
+			t.Errorf("UUIDv4 duplicate:%s", u)
+		}
+		seen[u] = struct{}{}
+	}
+}
+
+func TestUnescapeEscape(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		b := make([]byte, r.Intn(16))
+		r.Read(b)
+		s := string(b) + "\\x\u200b\U0001F642\U000E0001"
+		u, err := Unescape(Escape(s))
+		if err != nil {
+			t.Fatalf("Unescape error:%v", err)
+		}
+		if u != s {
+			t.Errorf("round trip not correct, expected:%q, got:%q", s, u)
+		}
+	}
+}
+
+func TestUnflattenMap(t *testing.T) {
+	var m map[string]interface{}
+	err := json.Unmarshal([]byte(`{"server":{"host":"example.com","port":8080},
+		"users":[{"name":"pat","roles":["admin","dev"]},{"name":"lee"}],
+		"matrix":[[1,2],[3]],"empty":{},"none":[],"obj":{"k":"v","1":true}}`), &m)
+	if err != nil {
+		t.Fatalf("Unmarshal error:%v", err)
+	}
+	u, err := UnflattenMap(FlattenMap(m, "/"), "/")
+	if err != nil {
+		t.Fatalf("UnflattenMap error:%v", err)
+	}
+	if !reflect.DeepEqual(u, m) {
+		t.Errorf("round trip not correct, expected:%v, got:%v", m, u)
+	}
+
+	// Empty containers merge with keys below them; sparse indexes stay a map.
+	u, err = UnflattenMap(map[string]interface{}{"a": map[string]interface{}{}, "a.b": 1,
+		"c": []interface{}{}, "c.0": 2, "d.0": 3, "d.2": 4}, ".")
+	if err != nil {
+		t.Fatalf("UnflattenMap error:%v", err)
+	}
+	expected := map[string]interface{}{"a": map[string]interface{}{"b": 1}, "c": []interface{}{2},
+		"d": map[string]interface{}{"0": 3, "2": 4}}
+	if !reflect.DeepEqual(u, expected) {
+		t.Errorf("merge not correct, expected:%v, got:%v", expected, u)
+	}
+
+	// The top level stays a map with numeric keys, and FlattenMap round trips; nested
+	// maps with numeric keys become arrays.
+	for _, test := range []struct {
+		top      map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{map[string]interface{}{"0": 1.0}, map[string]interface{}{"0": 1.0}},
+		{map[string]interface{}{"0": "a", "1": "b"}, map[string]interface{}{"0": "a", "1": "b"}},
+		{map[string]interface{}{"0": map[string]interface{}{"0": "x", "1": "y"}, "1": []interface{}{}},
+			map[string]interface{}{"0": []interface{}{"x", "y"}, "1": []interface{}{}}},
+	} {
+		u, err := UnflattenMap(FlattenMap(test.top, "."), ".")
+		if err != nil || !reflect.DeepEqual(u, test.expected) {
+			t.Errorf("numeric top level not correct, expected:%v, got:%v, err:%v", test.expected, u, err)
+		}
+	}
+
+	// Map values in input are returned unchanged, not converted to arrays.
+	value := map[string]interface{}{"0": "x"}
+	u, err = UnflattenMap(map[string]interface{}{"a": value, "b.0": "y"}, ".")
+	expected = map[string]interface{}{"a": map[string]interface{}{"0": "x"}, "b": []interface{}{"y"}}
+	if err != nil || !reflect.DeepEqual(u, expected) {
+		t.Errorf("input value not correct, expected:%v, got:%v, err:%v", expected, u, err)
+	}
+
+	for _, input := range []map[string]interface{}{
+		{"a": 1, "a.b": 2},
+		{"a.b": 1, "a.b.c": 2},
+		{"a": nil, "a.b": 2},
+		{"a": map[string]interface{}{"x": 1}, "a.b": 2},
+	} {
+		if _, err := UnflattenMap(input, "."); err == nil {
+			t.Errorf("expected conflict error for:%v", input)
+		}
+	}
+	if _, err := UnflattenMap(m, ""); err == nil {
+		t.Errorf("expected error for empty separator")
+	}
+}
+
+func TestUniqueStringsInputUnchanged(t *testing.T) {
+	input := []string{"a", "", " ", "a"}
+	original := append([]string{}, input...)
+	out, duplicates := UniqueStrings(input, "%s_%03d")
+	if strings.Join(out, "|") != "a|_|__002|a_002" || !duplicates {
+		t.Errorf("UniqueStrings not correct, got:%q, %v", out, duplicates)
+	}
+	for i := range input {
+		if input[i] != original[i] {
+			t.Errorf("UniqueStrings modified input, index:%d, expected:%q, got:%q", i, original[i], input[i])
+		}
+	}
+}
+
+func TestUniqueStringsExcluding(t *testing.T) {
+	existing := map[string]struct{}{"a": {}, "a_002": {}, "a_003": {}, "_": {}, "b_002": {}}
+	input := []string{"a", "b", "b", "b", "", "a", "c"}
+	out, duplicates := UniqueStringsExcluding(input, existing, "%s_%03d")
+	if !duplicates {
+		t.Error("duplicates not reported")
+	}
+	seen := map[string]struct{}{}
+	for _, o := range out {
+		if _, ok := existing[o]; ok {
+			t.Errorf("output collides with existing name:%s", o)
+		}
+		if _, ok := seen[o]; ok {
+			t.Errorf("duplicate output:%s", o)
+		}
+		seen[o] = struct{}{}
+	}
+	if strings.Join(out, "|") != "a_004|b|b_003|b_004|__002|a_005|c" {
+		t.Errorf("UniqueStringsExcluding not correct, got:%q", out)
+	}
+
+	// A numberFormat that ignores the number falls back to "%s_%d".
+	out, duplicates = UniqueStringsExcluding([]string{"a", "b", "b"}, map[string]struct{}{"a": {}, "a_2": {}}, "%[1]s")
+	if !duplicates || strings.Join(out, "|") != "a_3|b|b_2" {
+		t.Errorf("fallback format not correct, got:%q, duplicates:%v", out, duplicates)
+	}
+}
+
+func TestVerifyHMACSHA256(t *testing.T) {
+	key := []byte("webhook secret")
+	payload := []byte(`{"event":"push","ref":"main"}`)
+	mac := HMACSHA256(key, payload)
+	if !VerifyHMACSHA256(key, payload, mac) {
+		t.Error("valid MAC rejected")
+	}
+	if VerifyHMACSHA256(key, []byte(`{"event":"push","ref":"prod"}`), mac) {
+		t.Error("tampered message accepted")
+	}
+	if VerifyHMACSHA256([]byte("wrong secret"), payload, mac) {
+		t.Error("wrong key accepted")
+	}
+	if VerifyHMACSHA256(key, payload, mac[:16]) || VerifyHMACSHA256(key, payload, nil) {
+		t.Error("truncated MAC accepted")
+	}
+}
+
+func TestWeightedMovingAverage(t *testing.T) {
+	// Windows [1 2 3], [2 3 4], [3 4 5] weighted 1/6, 2/6, 3/6:
+	// (1+4+9)/6, (2+6+12)/6, (3+8+15)/6.
+	got, err := WeightedMovingAverage([]float64{1, 2, 3, 4, 5}, []float64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("WeightedMovingAverage error:%v", err)
+	}
+	expected := []float64{14.0 / 6, 20.0 / 6, 26.0 / 6}
+	if len(got) != len(expected) {
+		t.Fatalf("length not correct, expected:%v, got:%v", expected, got)
+	}
+	for i := range expected {
+		if math.Abs(got[i]-expected[i]) > 1e-9 {
+			t.Errorf("index %d not correct, expected:%v, got:%v", i, expected[i], got[i])
+		}
+	}
+
+	// Equal weights are a simple moving average; weights the length of in give one value.
+	got, err = WeightedMovingAverage([]float64{2, 4, 6}, []float64{0.5, 0.5, 0.5})
+	if err != nil || len(got) != 1 || math.Abs(got[0]-4) > 1e-9 {
+		t.Errorf("equal weights not correct, got:%v, err:%v", got, err)
+	}
+
+	for _, weights := range [][]float64{{}, {1, 1, 1, 1}, {1, -1}} {
+		if _, err := WeightedMovingAverage([]float64{1, 2, 3}, weights); err == nil {
+			t.Errorf("expected error for weights:%v", weights)
+		}
+	}
+}
+
+func TestWindowChecksums(t *testing.T) {
+	region := []byte("a region shared by both versions")
+	oldData := append(append([]byte("old header...."), region...), "old footer"...)
+	newData := append(append([]byte("a much longer new header"), region...), "new"...)
+	const window = 8
+
+	// Index every window of the old data, then find the new data's windows in it.
+	oldSums := WindowChecksums(oldData, window, 1)
+	if len(oldSums) != len(oldData)-window+1 {
+		t.Fatalf("window count not correct, expected:%d, got:%d", len(oldData)-window+1, len(oldSums))
+	}
+	index := map[uint32]int{}
+	for i, sum := range oldSums {
+		index[sum] = i
+	}
+	matches := 0
+	for i, sum := range WindowChecksums(newData, window, 1) {
+		if j, ok := index[sum]; ok {
+			if !bytes.Equal(oldData[j:j+window], newData[i:i+window]) {
+				t.Errorf("checksum matched different windows, old:%d, new:%d", j, i)
+			}
+			matches++
+		}
+	}
+	if expected := len(region) - window + 1; matches != expected {
+		t.Errorf("matching windows not correct, expected:%d, got:%d", expected, matches)
+	}
+
+	// Non-overlapping windows ignore the trailing partial window.
+	if sums := WindowChecksums([]byte("0123456789"), 4, 4); len(sums) != 2 {
+		t.Errorf("window count not correct, expected:2, got:%d", len(sums))
+	}
+	if WindowChecksums(region, 0, 1) != nil || WindowChecksums(region, 1, 0) != nil {
+		t.Error("invalid sizes did not return nil")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	for _, test := range []struct {
+		data []byte
+		perm os.FileMode
+	}{
+		{[]byte(`{"a":1}`), 0600},
+		// Overwrite an existing file, with a mode the umask would normally change.
+		{[]byte(`{"a":2,"b":3}`), 0666},
+	} {
+		if err := WriteFileAtomic(path, test.data, test.perm); err != nil {
+			t.Fatalf("WriteFileAtomic error:%v", err)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil || !bytes.Equal(b, test.data) {
+			t.Errorf("content not correct, expected:%s, got:%s, err:%v", test.data, b, err)
+		}
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != test.perm {
+			t.Errorf("mode not correct, expected:%v, got:%v, err:%v", test.perm, fi.Mode().Perm(), err)
+		}
+	}
+
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "config.json"), []byte("x"), 0600); err == nil {
+		t.Errorf("expected error for missing directory")
+	}
+	// Renaming over a directory fails; the temporary file must be removed.
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(filepath.Join(dir, "subdir"), []byte("x"), 0600); err == nil {
+		t.Errorf("expected error writing over a directory")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("temporary file not removed, entries:%v", entries)
 	}
 }
 